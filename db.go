@@ -3,11 +3,13 @@ package yiigo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"runtime/debug"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
@@ -60,9 +62,96 @@ func NewDBx(cfg *DBConfig) (*sqlx.DB, error) {
 	return sqlx.NewDb(db, cfg.Driver), nil
 }
 
+// TxOption 事务选项
+type TxOption func(o *txOptions)
+
+type txOptions struct {
+	opts     *sql.TxOptions
+	attempts int
+	backoff  time.Duration
+}
+
+// WithTxOptions 指定事务的隔离级别和是否只读
+func WithTxOptions(opts *sql.TxOptions) TxOption {
+	return func(o *txOptions) {
+		o.opts = opts
+	}
+}
+
+// WithTxRetry 指定事务在序列化失败或死锁时自动重试 (重新执行整个事务)；
+// attempts 为最大执行次数，backoff 为首次重试的等待时长，之后每次翻倍
+func WithTxRetry(attempts int, backoff time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+func newTxOptions(opts ...TxOption) *txOptions {
+	o := &txOptions{
+		attempts: 1,
+	}
+	for _, f := range opts {
+		f(o)
+	}
+	return o
+}
+
+// retry 按重试策略执行事务
+func (o *txOptions) retry(ctx context.Context, fn func() error) (err error) {
+	for i := 0; ; i++ {
+		err = fn()
+		if err == nil || i+1 >= o.attempts || !IsTxRetryableError(err) {
+			return
+		}
+
+		timer := time.NewTimer(o.backoff << i)
+		select {
+		case <-ctx.Done(): // timeout or canceled
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// IsTxRetryableError 判断是否可重试的事务错误 (Postgres: 40001, 40P01; MySQL: 1213, 1205)
+func IsTxRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01": // deadlock_detected
+			return true
+		}
+		return false
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1213, // ER_LOCK_DEADLOCK
+			1205: // ER_LOCK_WAIT_TIMEOUT
+			return true
+		}
+	}
+	return false
+}
+
 // Transaction 执行数据库事物
-func Transaction(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context, tx *sqlx.Tx) error) (err error) {
-	tx, _err := db.BeginTxx(ctx, nil)
+func Transaction(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context, tx *sqlx.Tx) error, opts ...TxOption) error {
+	o := newTxOptions(opts...)
+	return o.retry(ctx, func() error {
+		return transaction(ctx, db, fn, o.opts)
+	})
+}
+
+func transaction(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context, tx *sqlx.Tx) error, opts *sql.TxOptions) (err error) {
+	tx, _err := db.BeginTxx(ctx, opts)
 	if _err != nil {
 		err = fmt.Errorf("db.BeginTxx: %w", _err)
 		return
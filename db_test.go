@@ -0,0 +1,65 @@
+package yiigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTxRetryableError(t *testing.T) {
+	assert.False(t, IsTxRetryableError(nil))
+	assert.False(t, IsTxRetryableError(errors.New("oops")))
+	assert.True(t, IsTxRetryableError(&pgconn.PgError{Code: "40001"}))
+	assert.True(t, IsTxRetryableError(fmt.Errorf("committing transaction: %w", &pgconn.PgError{Code: "40P01"})))
+	assert.False(t, IsTxRetryableError(&pgconn.PgError{Code: "23505"}))
+	assert.True(t, IsTxRetryableError(&mysql.MySQLError{Number: 1213}))
+	assert.True(t, IsTxRetryableError(&mysql.MySQLError{Number: 1205}))
+	assert.False(t, IsTxRetryableError(&mysql.MySQLError{Number: 1062}))
+}
+
+func TestTransactionRetry(t *testing.T) {
+	ctx := context.Background()
+	db := sqliteDB(t)
+
+	attempts := 0
+	err := Transaction(ctx, db, func(ctx context.Context, tx *sqlx.Tx) error {
+		attempts++
+		if attempts < 3 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	}, WithTxRetry(3, time.Millisecond))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = NewSQLBuilder(db, nil).Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		attempts++
+		if _, err := tx.Wrap(Table("user")).Insert(ctx, X{"name": "yiigo"}); err != nil {
+			return err
+		}
+		return &mysql.MySQLError{Number: 1213}
+	}, WithTxRetry(2, time.Millisecond))
+	assert.True(t, IsTxRetryableError(err))
+	assert.Equal(t, 2, attempts)
+
+	// 不可重试的错误
+	attempts = 0
+	err = NewSQLBuilder(db, nil).Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		attempts++
+		return errors.New("oops")
+	}, WithTxRetry(3, time.Millisecond))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+
+	total, err := NewSQLBuilder(db, nil).Wrap(Table("user")).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), total)
+}
@@ -1,13 +1,23 @@
 package yiigo
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
 	"reflect"
+	"runtime/debug"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -17,57 +27,163 @@ var (
 
 	// ErrSQLBatchDataType 不合法的批量插入数据类型错误
 	ErrSQLBatchDataType = errors.New("invaild data type, expects: []struct, []*struct, []yiigo.X")
+
+	// ErrSQLCursor 不合法的分页游标错误
+	ErrSQLCursor = errors.New("invalid cursor")
+
+	// ErrSQLPageSize 不合法的分页大小错误 (需大于0)
+	ErrSQLPageSize = errors.New("invalid page size")
+
+	// ErrStaleVersion 乐观锁版本冲突错误 (数据已被修改或不存在)
+	ErrStaleVersion = errors.New("stale version")
 )
 
+// SQLSortFieldError 不合法的排序字段错误
+type SQLSortFieldError struct {
+	Field string
+}
+
+func (e *SQLSortFieldError) Error() string {
+	return "invalid sort field: " + e.Field
+}
+
 // ------------------------------------ TXBuilder ------------------------------------
 
 // TXBuilder 事务构造器
 type TXBuilder interface {
 	// Wrap 包装查询选项
 	Wrap(opts ...SQLOption) SQLWrapper
+	// Transaction 启用事务；在事务中调用时，使用 `SAVEPOINT` 实现嵌套事务 (内层失败仅回滚内层，且忽略事务选项)
+	Transaction(ctx context.Context, f func(ctx context.Context, tx TXBuilder) error, opts ...TxOption) error
+	// OnCommit 注册事务提交后的回调 (按注册顺序执行，Panic会被捕获)；非事务中调用时立即执行
+	OnCommit(fn func(ctx context.Context))
+	// OnRollback 注册事务回滚后的回调 (按注册顺序执行，Panic会被捕获)；非事务中调用时忽略
+	OnRollback(fn func(ctx context.Context, err error))
 	// 私有方法
 	one(ctx context.Context, dest any, query string, args ...any) error
 	all(ctx context.Context, dest any, query string, args ...any) error
+	query(ctx context.Context, query string, args ...any) (*sqlx.Rows, error)
 	exec(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
 type txBuilder struct {
-	tx  *sqlx.Tx
-	log func(ctx context.Context, query string, args ...any)
+	tx        *sqlx.Tx
+	dialect   Dialect
+	scopes    map[string][]*sqlScope
+	hooks     []SQLHook
+	panicFn   TxPanicFn
+	depth     int // 嵌套层级
+	commits   []func(ctx context.Context)
+	rollbacks []func(ctx context.Context, err error)
 }
 
 func (b *txBuilder) Wrap(opts ...SQLOption) SQLWrapper {
-	wrapper := &sqlWrapper{
-		tx: b,
+	return newSQLWrapper(b, b.dialect, b.scopes, opts)
+}
+
+func (b *txBuilder) Transaction(ctx context.Context, fn func(ctx context.Context, tx TXBuilder) error, _ ...TxOption) error {
+	nested := &txBuilder{
+		tx:      b.tx,
+		dialect: b.dialect,
+		scopes:  b.scopes,
+		hooks:   b.hooks,
+		panicFn: b.panicFn,
+		depth:   b.depth + 1,
 	}
-	for _, f := range opts {
-		f(wrapper)
+
+	savepoint, rollback, release := savepointSQL(b.dialect, fmt.Sprintf("yiigo_sp_%d", nested.depth))
+
+	if _, err := b.exec(ctx, savepoint); err != nil {
+		return err
 	}
-	return wrapper
+
+	defer func() {
+		if v := recover(); v != nil {
+			_, _ = b.exec(ctx, rollback)
+			nested.afterRollback(ctx, fmt.Errorf("transaction panic: %v", v))
+			panic(v)
+		}
+	}()
+
+	if err := fn(ctx, nested); err != nil {
+		if _, rerr := b.exec(ctx, rollback); rerr != nil {
+			err = fmt.Errorf("%w: rolling back to savepoint: %v", err, rerr)
+		}
+		nested.afterRollback(ctx, err)
+		return err
+	}
+	if len(release) != 0 {
+		if _, err := b.exec(ctx, release); err != nil {
+			err = fmt.Errorf("releasing savepoint: %w", err)
+			nested.afterRollback(ctx, err)
+			return err
+		}
+	}
+
+	// 内层回调随外层事务执行
+	b.commits = append(b.commits, nested.commits...)
+	b.rollbacks = append(b.rollbacks, nested.rollbacks...)
+
+	return nil
 }
 
-func (b *txBuilder) one(ctx context.Context, dest any, query string, args ...any) error {
-	query = sqlx.Rebind(sqlx.BindType(b.tx.DriverName()), query)
-	if b.log != nil {
-		b.log(ctx, query, args...)
+func (b *txBuilder) OnCommit(fn func(ctx context.Context)) {
+	b.commits = append(b.commits, fn)
+}
+
+func (b *txBuilder) OnRollback(fn func(ctx context.Context, err error)) {
+	b.rollbacks = append(b.rollbacks, fn)
+}
+
+func (b *txBuilder) afterCommit(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+	for _, fn := range b.commits {
+		safeTxCallback(ctx, b.panicFn, func() {
+			fn(ctx)
+		})
 	}
-	return b.tx.GetContext(ctx, dest, query, args...)
 }
 
-func (b *txBuilder) all(ctx context.Context, dest any, query string, args ...any) error {
-	query = sqlx.Rebind(sqlx.BindType(b.tx.DriverName()), query)
-	if b.log != nil {
-		b.log(ctx, query, args...)
+func (b *txBuilder) afterRollback(ctx context.Context, err error) {
+	ctx = context.WithoutCancel(ctx)
+	for _, fn := range b.rollbacks {
+		safeTxCallback(ctx, b.panicFn, func() {
+			fn(ctx, err)
+		})
 	}
-	return b.tx.SelectContext(ctx, dest, query, args...)
+}
+
+func (b *txBuilder) one(ctx context.Context, dest any, query string, args ...any) error {
+	query = sqlx.Rebind(b.dialect.BindType(), query)
+	_, err := runSQLHooks(ctx, b.hooks, query, args, func(ctx context.Context) (sql.Result, error) {
+		return nil, b.tx.GetContext(ctx, dest, query, args...)
+	})
+	return err
+}
+
+func (b *txBuilder) all(ctx context.Context, dest any, query string, args ...any) error {
+	query = sqlx.Rebind(b.dialect.BindType(), query)
+	_, err := runSQLHooks(ctx, b.hooks, query, args, func(ctx context.Context) (sql.Result, error) {
+		return nil, b.tx.SelectContext(ctx, dest, query, args...)
+	})
+	return err
+}
+
+func (b *txBuilder) query(ctx context.Context, query string, args ...any) (rows *sqlx.Rows, err error) {
+	query = sqlx.Rebind(b.dialect.BindType(), query)
+	_, err = runSQLHooks(ctx, b.hooks, query, args, func(ctx context.Context) (sql.Result, error) {
+		var err error
+		rows, err = b.tx.QueryxContext(ctx, query, args...)
+		return nil, err
+	})
+	return
 }
 
 func (b *txBuilder) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	query = sqlx.Rebind(sqlx.BindType(b.tx.DriverName()), query)
-	if b.log != nil {
-		b.log(ctx, query, args...)
-	}
-	return b.tx.ExecContext(ctx, query, args...)
+	query = sqlx.Rebind(b.dialect.BindType(), query)
+	return runSQLHooks(ctx, b.hooks, query, args, func(ctx context.Context) (sql.Result, error) {
+		return b.tx.ExecContext(ctx, query, args...)
+	})
 }
 
 // ------------------------------------ SQLBuilder ------------------------------------
@@ -75,83 +191,289 @@ func (b *txBuilder) exec(ctx context.Context, query string, args ...any) (sql.Re
 // SQLBuilder SQL构造器
 type SQLBuilder interface {
 	TXBuilder
-	// Transaction 启用事务
-	Transaction(ctx context.Context, f func(ctx context.Context, tx TXBuilder) error) error
+	// CopyFrom 使用 Postgres `COPY FROM` 批量导入数据 (数据类型：`[]struct`, `[]*struct`, `[]yiigo.X`)，返回导入行数；
+	// 仅支持 pgx 驱动，字段映射同 `BatchInsert`
+	CopyFrom(ctx context.Context, table string, data any) (int64, error)
 }
 
 type sqlBuilder struct {
-	db  *sqlx.DB
-	log func(ctx context.Context, query string, args ...any)
+	db       *sqlx.DB
+	dialect  Dialect
+	scopes   map[string][]*sqlScope
+	replicas []*sqlx.DB
+	policy   ReplicaPolicy
+	hooks    []SQLHook
+	panicFn  TxPanicFn
 }
 
 func (b *sqlBuilder) Wrap(opts ...SQLOption) SQLWrapper {
-	wrapper := &sqlWrapper{
-		tx: b,
-	}
-	for _, f := range opts {
-		f(wrapper)
-	}
-	return wrapper
+	return newSQLWrapper(b, b.dialect, b.scopes, opts)
 }
 
-func (b *sqlBuilder) Transaction(ctx context.Context, fn func(ctx context.Context, tx TXBuilder) error) error {
-	tx, err := b.db.BeginTxx(ctx, nil)
+func (b *sqlBuilder) Transaction(ctx context.Context, fn func(ctx context.Context, tx TXBuilder) error, opts ...TxOption) error {
+	o := newTxOptions(opts...)
+	return o.retry(ctx, func() error {
+		return b.transaction(ctx, fn, o.opts)
+	})
+}
+
+func (b *sqlBuilder) transaction(ctx context.Context, fn func(ctx context.Context, tx TXBuilder) error, opts *sql.TxOptions) error {
+	tx, err := b.db.BeginTxx(ctx, opts)
 	if err != nil {
 		return err
 	}
 
+	txb := &txBuilder{
+		tx:      tx,
+		dialect: b.dialect,
+		scopes:  b.scopes,
+		hooks:   b.hooks,
+		panicFn: b.panicFn,
+	}
+
 	defer func() {
 		if v := recover(); v != nil {
 			_ = tx.Rollback()
+			txb.afterRollback(ctx, fmt.Errorf("transaction panic: %v", v))
 			panic(v)
 		}
 	}()
 
-	if err = fn(ctx, &txBuilder{
-		tx:  tx,
-		log: b.log,
-	}); err != nil {
+	if err = fn(ctx, txb); err != nil {
 		if rerr := tx.Rollback(); rerr != nil {
 			err = fmt.Errorf("%w: rolling back transaction: %v", err, rerr)
 		}
+		txb.afterRollback(ctx, err)
 		return err
 	}
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("committing transaction: %w", err)
+		err = fmt.Errorf("committing transaction: %w", err)
+		txb.afterRollback(ctx, err)
+		return err
 	}
+	txb.afterCommit(ctx)
 	return nil
 }
 
-func (b *sqlBuilder) one(ctx context.Context, dest any, query string, args ...any) error {
-	query = sqlx.Rebind(sqlx.BindType(b.db.DriverName()), query)
-	if b.log != nil {
-		b.log(ctx, query, args...)
+func (b *sqlBuilder) CopyFrom(ctx context.Context, table string, data any) (int64, error) {
+	if b.dialect.Name() != dialectPostgres {
+		return 0, fmt.Errorf("copy from is not supported by dialect: %s", b.dialect.Name())
+	}
+
+	w := &sqlWrapper{
+		tx:      b,
+		dialect: b.dialect,
+		table:   table,
+		now:     time.Now(),
 	}
-	return b.db.GetContext(ctx, dest, query, args...)
+	columns, args, err := w.batchInsertValues(data)
+	if err != nil {
+		return 0, err
+	}
+
+	l := len(columns)
+	if l == 0 {
+		return 0, errors.New("err empty columns")
+	}
+
+	conn, err := b.db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	// 仅用于钩子 (如：日志)，实际由 pgx 生成
+	quoted := make([]string, 0, l)
+	for _, v := range columns {
+		quoted = append(quoted, quoteColumn(b.dialect, v))
+	}
+	query := "COPY " + quoteColumn(b.dialect, table) + " (" + strings.Join(quoted, ", ") + ") FROM STDIN"
+
+	var rows int64
+	_, err = runSQLHooks(ctx, b.hooks, query, nil, func(ctx context.Context) (sql.Result, error) {
+		err := conn.Raw(func(driverConn any) error {
+			c, ok := driverConn.(*stdlib.Conn)
+			if !ok {
+				return fmt.Errorf("copy from is not supported by driver: %s", b.db.DriverName())
+			}
+
+			var err error
+			rows, err = c.Conn().CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, copyFromRows(args, l))
+			return err
+		})
+		return driver.RowsAffected(rows), err
+	})
+	if err != nil {
+		return 0, err
+	}
+	fillAutoTime(data, w.now, false)
+
+	return rows, nil
+}
+
+// copyFromRows 将按行展开的参数转换为 `COPY FROM` 的数据源
+func copyFromRows(args []any, columns int) pgx.CopyFromSource {
+	return pgx.CopyFromSlice(len(args)/columns, func(i int) ([]any, error) {
+		return args[i*columns : (i+1)*columns], nil
+	})
+}
+
+func (b *sqlBuilder) OnCommit(fn func(ctx context.Context)) {
+	safeTxCallback(context.Background(), b.panicFn, func() {
+		fn(context.Background())
+	})
+}
+
+func (b *sqlBuilder) OnRollback(fn func(ctx context.Context, err error)) {}
+
+func (b *sqlBuilder) one(ctx context.Context, dest any, query string, args ...any) error {
+	query = sqlx.Rebind(b.dialect.BindType(), query)
+	_, err := runSQLHooks(ctx, b.hooks, query, args, func(ctx context.Context) (sql.Result, error) {
+		db, done := b.reader(ctx)
+		defer done()
+		return nil, db.GetContext(ctx, dest, query, args...)
+	})
+	return err
 }
 
 func (b *sqlBuilder) all(ctx context.Context, dest any, query string, args ...any) error {
-	query = sqlx.Rebind(sqlx.BindType(b.db.DriverName()), query)
-	if b.log != nil {
-		b.log(ctx, query, args...)
+	query = sqlx.Rebind(b.dialect.BindType(), query)
+	_, err := runSQLHooks(ctx, b.hooks, query, args, func(ctx context.Context) (sql.Result, error) {
+		db, done := b.reader(ctx)
+		defer done()
+		return nil, db.SelectContext(ctx, dest, query, args...)
+	})
+	return err
+}
+
+func (b *sqlBuilder) query(ctx context.Context, query string, args ...any) (rows *sqlx.Rows, err error) {
+	query = sqlx.Rebind(b.dialect.BindType(), query)
+	_, err = runSQLHooks(ctx, b.hooks, query, args, func(ctx context.Context) (sql.Result, error) {
+		db, done := b.reader(ctx)
+		defer done()
+
+		var err error
+		rows, err = db.QueryxContext(ctx, query, args...)
+		return nil, err
+	})
+	return
+}
+
+// reader 返回用于查询的数据库 (从库 or 主库)，done 用于上报查询耗时
+func (b *sqlBuilder) reader(ctx context.Context) (db *sqlx.DB, done func()) {
+	if len(b.replicas) == 0 || isForcePrimary(ctx) {
+		return b.db, func() {}
+	}
+
+	i := b.policy.Pick(len(b.replicas))
+	now := time.Now()
+	return b.replicas[i], func() {
+		b.policy.Observe(i, time.Since(now))
 	}
-	return b.db.SelectContext(ctx, dest, query, args...)
 }
 
 func (b *sqlBuilder) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	query = sqlx.Rebind(sqlx.BindType(b.db.DriverName()), query)
-	if b.log != nil {
-		b.log(ctx, query, args...)
+	query = sqlx.Rebind(b.dialect.BindType(), query)
+	return runSQLHooks(ctx, b.hooks, query, args, func(ctx context.Context) (sql.Result, error) {
+		return b.db.ExecContext(ctx, query, args...)
+	})
+}
+
+// SQLBuilderOption SQL构造器选项
+type SQLBuilderOption func(b *sqlBuilder)
+
+// WithSQLHook 指定SQL执行钩子
+func WithSQLHook(hooks ...SQLHook) SQLBuilderOption {
+	return func(b *sqlBuilder) {
+		b.hooks = append(b.hooks, hooks...)
+	}
+}
+
+// WithSQLLogger 指定SQL执行前的日志打印方法
+func WithSQLLogger(fn func(ctx context.Context, query string, args ...any)) SQLBuilderOption {
+	return func(b *sqlBuilder) {
+		if fn != nil {
+			b.hooks = append(b.hooks, SQLLogHook(fn))
+		}
+	}
+}
+
+// WithTxPanicFn 指定事务回调 (`OnCommit`, `OnRollback`) 发生Panic的处理方法
+func WithTxPanicFn(fn TxPanicFn) SQLBuilderOption {
+	return func(b *sqlBuilder) {
+		b.panicFn = fn
+	}
+}
+
+// WithDialect 指定SQL方言 (默认根据驱动名称确定)
+func WithDialect(d Dialect) SQLBuilderOption {
+	return func(b *sqlBuilder) {
+		b.dialect = d
+	}
+}
+
+// WithSQLScope 指定表的全局作用域，`Wrap` 时自动应用 (在查询选项之后)，可使用 `yiigo.Unscoped` 忽略；
+// 例如：yiigo.WithSQLScope("user", "active", yiigo.Where("status = ?", 1))
+func WithSQLScope(table, name string, opts ...SQLOption) SQLBuilderOption {
+	return func(b *sqlBuilder) {
+		if b.scopes == nil {
+			b.scopes = make(map[string][]*sqlScope)
+		}
+		b.scopes[table] = append(b.scopes[table], &sqlScope{
+			name: name,
+			opts: opts,
+		})
+	}
+}
+
+// WithSoftDelete 指定表使用软删除 (作用域名称：`yiigo.SoftDeleteScope`)；
+// 查询、更新时自动添加 `column IS NULL` 条件，`Delete` 变为 `UPDATE ... SET column = CURRENT_TIMESTAMP`
+func WithSoftDelete(column string, tables ...string) SQLBuilderOption {
+	return func(b *sqlBuilder) {
+		for _, table := range tables {
+			WithSQLScope(table, SoftDeleteScope, softDelete(column))(b)
+		}
 	}
-	return b.db.ExecContext(ctx, query, args...)
 }
 
-// NewSQLBuilder 生成SQL构造器
+// WithSQLReplicas 指定从库 (默认轮询选择)；
+// `One`, `All` 等查询在从库执行，写操作、事务和加锁查询 (如：`ForUpdate`) 在主库执行，可使用 `yiigo.ForcePrimary` 强制在主库查询
+func WithSQLReplicas(replicas ...*sqlx.DB) SQLBuilderOption {
+	return func(b *sqlBuilder) {
+		b.replicas = append(b.replicas, replicas...)
+	}
+}
+
+// WithReplicaPolicy 指定从库选择策略
+func WithReplicaPolicy(policy ReplicaPolicy) SQLBuilderOption {
+	return func(b *sqlBuilder) {
+		b.policy = policy
+	}
+}
+
+// NewSQLBuilder 生成SQL构造器，logFn 为SQL执行前的日志打印方法 (可为 nil)；
+// 如需指定 Hook、从库、方言等选项，请使用 `NewSQLBuilderWithOptions`
 func NewSQLBuilder(db *sqlx.DB, logFn func(ctx context.Context, query string, args ...any)) SQLBuilder {
-	return &sqlBuilder{
-		db:  db,
-		log: logFn,
+	return NewSQLBuilderWithOptions(db, WithSQLLogger(logFn))
+}
+
+// NewSQLBuilderWithOptions 根据选项生成SQL构造器 (db 为主库)
+func NewSQLBuilderWithOptions(db *sqlx.DB, opts ...SQLBuilderOption) SQLBuilder {
+	builder := &sqlBuilder{
+		db: db,
 	}
+	for _, f := range opts {
+		if f != nil {
+			f(builder)
+		}
+	}
+	if builder.dialect == nil {
+		builder.dialect = NewDialect(db.DriverName())
+	}
+	if len(builder.replicas) != 0 && builder.policy == nil {
+		builder.policy = RoundRobinPolicy()
+	}
+	return builder
 }
 
 // ------------------------------------ SQLWrapper ------------------------------------
@@ -162,16 +484,132 @@ type SQLWrapper interface {
 	One(ctx context.Context, data any) error
 	// All 查询多条数据
 	All(ctx context.Context, data any) error
-	// Insert 插入数据 (数据类型：`struct`, `*struct`, `yiigo.X`)
+	// Insert 插入数据 (数据类型：`struct`, `*struct`, `yiigo.X`)；
+	// 结构体中标记 `autoCreateTime`, `autoUpdateTime` 的零值字段自动填充当前时间 (执行成功后回填)，标记 `pk` 的零值字段不插入
 	Insert(ctx context.Context, data any) (sql.Result, error)
-	// BatchInsert 批量插入数据 (数据类型：`[]struct`, `[]*struct`, `[]yiigo.X`)
+	// BatchInsert 批量插入数据 (数据类型：`[]struct`, `[]*struct`, `[]yiigo.X`)；
+	// 结构体的插入字段由首行决定，标记 `pk` 的字段各行需同为零值或同为非零值
 	BatchInsert(ctx context.Context, data any) (sql.Result, error)
-	// Update 更新数据 (数据类型：`struct`, `*struct`, `yiigo.X`)
+	// InsertFrom 插入子查询的结果，即：INSERT INTO ... (columns) SELECT ...
+	InsertFrom(ctx context.Context, columns []string, sub SQLWrapper) (sql.Result, error)
+	// ChunkInsert 分批插入数据 (数据类型同 `BatchInsert`)，返回影响的总行数；
+	// 每批最多 size 行 (<=0 表示不限制)，且不超过方言的参数数量限制；多批时在同一事务中执行 (已在事务中则直接使用该事务)
+	ChunkInsert(ctx context.Context, data any, size int) (int64, error)
+	// Update 更新数据 (数据类型：`struct`, `*struct`, `yiigo.X`)；
+	// 结构体中标记 `autoUpdateTime` 的字段更新为当前时间 (执行成功后回填)，`pk` 和 `autoCreateTime` 的字段不更新；
+	// 标记 `version` 的字段用于乐观锁，未更新到数据时返回 `yiigo.ErrStaleVersion`；
+	// 指定 `JOIN` 时为多表更新 (MySQL: `UPDATE ... JOIN`，Postgres 和 SQLite: `UPDATE ... FROM`，仅支持 `INNER JOIN` 和 `CROSS JOIN`)
 	Update(ctx context.Context, data any) (sql.Result, error)
-	// Delete 删除数据
+	// UpdateFields 仅更新指定字段 (数据类型：`struct`, `*struct`)，忽略 `omitempty`，可用于更新为零值；
+	// fields 为字段名 (db 标签名)，可使用 `yiigo.SQLDiff` 获取变更的字段，其余同 `Update`
+	UpdateFields(ctx context.Context, data any, fields ...string) (sql.Result, error)
+	// Delete 删除数据 (软删除的表执行 `UPDATE`)；
+	// 指定 `JOIN` 时仅删除主表数据 (MySQL: `DELETE t FROM t JOIN`，Postgres: `DELETE ... USING` 仅支持 `INNER JOIN` 和 `CROSS JOIN`，SQLite: rowid 子查询)
 	Delete(ctx context.Context) (sql.Result, error)
 	// Truncate 清空表
 	Truncate(ctx context.Context) (sql.Result, error)
+	// Count 查询数量 (忽略 `ORDER BY`, `LIMIT` 和 `OFFSET`)
+	Count(ctx context.Context) (int64, error)
+	// Exists 判断数据是否存在
+	Exists(ctx context.Context) (bool, error)
+	// Pluck 查询单个字段的值 (数据类型：`*[]T`)
+	Pluck(ctx context.Context, column string, dest any) error
+	// Paginate 分页查询并返回总数 (page 从1开始，size 需大于0，否则返回 `yiigo.ErrSQLPageSize`)
+	Paginate(ctx context.Context, page, size int, dest any) (int64, error)
+	// Keyset 游标分页查询 (数据类型：`*[]struct`, `*[]*struct`, `*[]yiigo.X`)，返回下一页游标 (为空表示无更多数据)；
+	// keys 为排序字段，需能唯一确定一行，`-` 前缀表示降序，例如：[]string{"-created_at", "-id"}；size 需大于0，否则返回 `yiigo.ErrSQLPageSize`
+	Keyset(ctx context.Context, keys []string, cursor string, size int, dest any) (string, error)
+	// Rows 查询并返回结果集游标，用于逐行读取大量数据 (需调用 `Close` 关闭)
+	Rows(ctx context.Context) (*sqlx.Rows, error)
+	// Explain 返回查询语句的执行计划 (SQLite 使用 `EXPLAIN QUERY PLAN`)
+	Explain(ctx context.Context) ([]X, error)
+	// ToQuery 返回查询语句及参数 (不执行)
+	ToQuery() (string, []any, error)
+	// ToInsert 返回插入语句及参数 (不执行)
+	ToInsert(data any) (string, []any, error)
+	// ToBatchInsert 返回批量插入语句及参数 (不执行)
+	ToBatchInsert(data any) (string, []any, error)
+	// ToInsertFrom 返回 `INSERT INTO ... SELECT` 语句及参数 (不执行)
+	ToInsertFrom(columns []string, sub SQLWrapper) (string, []any, error)
+	// ToUpdate 返回更新语句及参数 (不执行)
+	ToUpdate(data any) (string, []any, error)
+	// ToDelete 返回删除语句及参数 (不执行)
+	ToDelete() (string, []any, error)
+	// ToTruncate 返回清空表语句 (不执行)
+	ToTruncate() string
+}
+
+// SQLIter 逐行读取查询结果 (数据类型：`struct`, `*struct`, `yiigo.X` 或单字段的基础类型)，
+// 结束或中途退出时自动关闭结果集，例如：
+//
+//	for row, err := range yiigo.SQLIter[User](ctx, builder.Wrap(...)) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func SQLIter[T any](ctx context.Context, w SQLWrapper) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		rows, err := w.Rows(ctx)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row T
+			if err = scanRow(rows, &row); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(row, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// SQLEach 逐行读取查询结果并执行 fn，fn 返回错误时停止读取并返回该错误
+func SQLEach[T any](ctx context.Context, w SQLWrapper, fn func(row T) error) error {
+	for row, err := range SQLIter[T](ctx, w) {
+		if err != nil {
+			return err
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanRow 将当前行扫描到 dest (指针)
+func scanRow(rows *sqlx.Rows, dest any) error {
+	v := reflect.ValueOf(dest).Elem()
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		dest = v.Interface()
+		v = v.Elem()
+	}
+
+	if x, ok := dest.(*X); ok {
+		m := make(map[string]any)
+		if err := rows.MapScan(m); err != nil {
+			return err
+		}
+		*x = m
+		return nil
+	}
+
+	if _, ok := dest.(sql.Scanner); !ok && v.Kind() == reflect.Struct && v.Type() != reflect.TypeOf(time.Time{}) {
+		return rows.StructScan(dest)
+	}
+	return rows.Scan(dest)
 }
 
 // SQLClause SQL语句
@@ -180,6 +618,7 @@ type SQLClause struct {
 	keyword string
 	query   string
 	binds   []any
+	build   func(d Dialect) (*SQLClause, error) // 方言相关的表达式
 }
 
 // SQLExpr 生成一个语句表达式，例如：yiigo.SQLExpr("price * ? + ?", 2, 100)
@@ -190,213 +629,1207 @@ func SQLExpr(query string, binds ...any) *SQLClause {
 	}
 }
 
-type sqlWrapper struct {
-	tx        TXBuilder
-	table     string
-	columns   []string
-	where     []*SQLClause
-	joins     []*SQLClause
-	groups    []string
-	having    []*SQLClause
-	orders    []string
-	offset    int
-	limit     int
-	returning []string
-	unions    []*SQLClause
-	distinct  bool
-	whereIn   bool
+// As 指定表达式的别名，用于 `SelectExpr`，例如：yiigo.SQLExpr("COUNT(*)").As("total")
+func (c *SQLClause) As(alias string) *SQLClause {
+	return &SQLClause{
+		build: func(d Dialect) (*SQLClause, error) {
+			v, err := c.resolve(d)
+			if err != nil {
+				return nil, err
+			}
+			return &SQLClause{
+				query: v.query + " AS " + d.Quote(alias),
+				binds: v.binds,
+			}, nil
+		},
+	}
 }
 
-func (w *sqlWrapper) One(ctx context.Context, dest any) error {
-	query, args, err := w.querySQL()
-	if err != nil {
-		return err
+// resolve 根据方言生成表达式
+func (c *SQLClause) resolve(d Dialect) (*SQLClause, error) {
+	if c.build == nil {
+		return c, nil
 	}
-	return w.tx.one(ctx, dest, query, args...)
+	return c.build(d)
 }
 
-func (w *sqlWrapper) All(ctx context.Context, dest any) error {
-	query, args, err := w.querySQL()
-	if err != nil {
-		return err
-	}
-	return w.tx.all(ctx, dest, query, args...)
+// SQLCondition 条件组，用于构造 `AND` / `OR` 嵌套条件
+type SQLCondition struct {
+	dialect Dialect
+	clauses []*SQLClause
+	whereIn bool
+	err     error
 }
 
-func (w *sqlWrapper) Insert(ctx context.Context, data any) (sql.Result, error) {
-	query, args, err := w.insertSQL(data)
-	if err != nil {
-		return nil, err
-	}
-	return w.tx.exec(ctx, query, args...)
+// Where 指定 `AND` 条件
+func (c *SQLCondition) Where(query string, binds ...any) *SQLCondition {
+	c.add("AND", query, binds)
+	return c
 }
 
-func (w *sqlWrapper) BatchInsert(ctx context.Context, data any) (sql.Result, error) {
-	query, args, err := w.batchInsertSQL(data)
-	if err != nil {
-		return nil, err
-	}
-	return w.tx.exec(ctx, query, args...)
+// OrWhere 指定 `OR` 条件
+func (c *SQLCondition) OrWhere(query string, binds ...any) *SQLCondition {
+	c.add("OR", query, binds)
+	return c
 }
 
-func (w *sqlWrapper) Update(ctx context.Context, data any) (sql.Result, error) {
-	query, args, err := w.updateSQL(data)
+func (c *SQLCondition) add(keyword, query string, binds []any) {
+	clause, whereIn, err := newSQLClause(c.dialect, keyword, query, binds)
 	if err != nil {
-		return nil, err
+		c.err = err
+		return
 	}
-	return w.tx.exec(ctx, query, args...)
+	c.clauses = append(c.clauses, clause)
+	c.whereIn = c.whereIn || whereIn
 }
 
-func (w *sqlWrapper) Delete(ctx context.Context) (sql.Result, error) {
-	query, args, err := w.deleteSQL()
-	if err != nil {
-		return nil, err
-	}
-	return w.tx.exec(ctx, query, args...)
+// WhereIn 指定 `AND` 的 `IN` 条件
+func (c *SQLCondition) WhereIn(query string, binds ...any) *SQLCondition {
+	c.whereIn = true
+	return c.Where(query, binds...)
 }
 
-func (w *sqlWrapper) Truncate(ctx context.Context) (sql.Result, error) {
-	return w.tx.exec(ctx, w.truncateSQL())
+// OrWhereIn 指定 `OR` 的 `IN` 条件
+func (c *SQLCondition) OrWhereIn(query string, binds ...any) *SQLCondition {
+	c.whereIn = true
+	return c.OrWhere(query, binds...)
 }
 
-func (w *sqlWrapper) querySQL() (sql string, args []any, err error) {
-	sql, args = w.subquery()
+// Group 指定 `AND` 嵌套条件组
+func (c *SQLCondition) Group(fn func(c *SQLCondition)) *SQLCondition {
+	c.addGroup("AND", fn)
+	return c
+}
 
-	// unions
-	if l := len(w.unions); l != 0 {
-		var builder strings.Builder
+// OrGroup 指定 `OR` 嵌套条件组
+func (c *SQLCondition) OrGroup(fn func(c *SQLCondition)) *SQLCondition {
+	c.addGroup("OR", fn)
+	return c
+}
 
-		builder.WriteString("(")
-		builder.WriteString(sql)
-		builder.WriteString(")")
+func (c *SQLCondition) addGroup(keyword string, fn func(c *SQLCondition)) {
+	clause, whereIn, err := newConditionGroup(c.dialect, keyword, fn)
+	if err != nil {
+		c.err = err
+		return
+	}
+	if clause != nil {
+		c.clauses = append(c.clauses, clause)
+		c.whereIn = c.whereIn || whereIn
+	}
+}
 
-		for _, v := range w.unions {
-			builder.WriteString(" ")
-			builder.WriteString(v.keyword)
-			builder.WriteString(" (")
-			builder.WriteString(v.query)
-			builder.WriteString(")")
+// newSQLClause 生成条件语句；
+// 若参数为 `SQLWrapper`（子查询）或 `yiigo.SQLExpr`（表达式），则将对应的占位符 `?` 替换为其语句，并按位置合并参数
+func newSQLClause(d Dialect, keyword, query string, binds []any) (*SQLClause, bool, error) {
+	clause := &SQLClause{
+		keyword: keyword,
+		query:   query,
+		binds:   binds,
+	}
 
-			args = append(args, v.binds...)
+	expand := false
+	for _, v := range binds {
+		switch v.(type) {
+		case *sqlWrapper, *SQLClause:
+			expand = true
 		}
-
-		sql = builder.String()
+	}
+	if !expand {
+		return clause, false, nil
 	}
 
-	// where in
-	if w.whereIn {
-		sql, args, err = sqlx.In(sql, args...)
-		if err != nil {
-			return
+	var (
+		builder strings.Builder
+		whereIn bool
+	)
+
+	args := make([]any, 0, len(binds))
+
+	index := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c != '?' {
+			builder.WriteByte(c)
+			continue
 		}
+		if index >= len(binds) {
+			builder.WriteByte(c)
+			continue
+		}
+
+		switch v := binds[index].(type) {
+		case *sqlWrapper:
+			if v.err != nil {
+				return nil, false, v.err
+			}
+			sql, subArgs := v.selectSQL()
+			builder.WriteString(sql)
+			args = append(args, subArgs...)
+			whereIn = whereIn || v.whereIn
+		case *SQLClause:
+			expr, err := v.resolve(d)
+			if err != nil {
+				return nil, false, err
+			}
+			builder.WriteString(expr.query)
+			args = append(args, expr.binds...)
+		default:
+			builder.WriteByte(c)
+			args = append(args, v)
+		}
+		index++
 	}
+	// 多余的参数
+	args = append(args, binds[index:]...)
 
-	return
+	clause.query = builder.String()
+	clause.binds = args
+
+	return clause, whereIn, nil
 }
 
-func (w *sqlWrapper) subquery() (string, []any) {
-	args := make([]any, 0)
+func newConditionGroup(d Dialect, keyword string, fn func(c *SQLCondition)) (*SQLClause, bool, error) {
+	cond := &SQLCondition{dialect: d}
+	fn(cond)
+	if cond.err != nil {
+		return nil, false, cond.err
+	}
+	if len(cond.clauses) == 0 {
+		return nil, false, nil
+	}
 
 	var builder strings.Builder
 
-	builder.WriteString("SELECT ")
-	if w.distinct {
-		builder.WriteString("DISTINCT ")
+	binds := writeConditions(&builder, cond.clauses)
+
+	return &SQLClause{
+		keyword: keyword,
+		query:   builder.String(),
+		binds:   binds,
+	}, cond.whereIn, nil
+}
+
+// condition 生成条件语句，并记录 `IN` 子句和错误
+func (w *sqlWrapper) condition(keyword, query string, binds []any) *SQLClause {
+	clause, whereIn, err := newSQLClause(w.dialect, keyword, query, binds)
+	if err != nil {
+		w.err = err
+		return nil
 	}
+	w.whereIn = w.whereIn || whereIn
+	return clause
+}
 
-	// columns
-	if len(w.columns) == 0 {
-		builder.WriteString("*")
-	} else {
-		builder.WriteString(w.columns[0])
-		for _, column := range w.columns[1:] {
-			builder.WriteString(", ")
-			builder.WriteString(column)
-		}
+// conditionGroup 生成嵌套条件组，并记录 `IN` 子句和错误
+func (w *sqlWrapper) conditionGroup(keyword string, fn func(c *SQLCondition)) *SQLClause {
+	clause, whereIn, err := newConditionGroup(w.dialect, keyword, fn)
+	if err != nil {
+		w.err = err
+		return nil
 	}
+	w.whereIn = w.whereIn || whereIn
+	return clause
+}
 
-	// from
-	builder.WriteString(" FROM ")
-	builder.WriteString(w.table)
+type sqlWrapper struct {
+	tx          TXBuilder
+	dialect     Dialect
+	table       string
+	tableBinds  []any
+	columns     []string
+	columnBinds []any
+	where       []*SQLClause
+	joins       []*SQLClause
+	groups      []string
+	having      []*SQLClause
+	orders      []string
+	offset      int
+	limit       int
+	returning   []string
+	lock        string
+	unions      []*SQLClause
+	upsert      *sqlUpsert
+	ctes        []*SQLClause
+	recursive   bool
+	distinct    bool
+	whereIn     bool
+	softDelete  string
+	unscoped    []string
+	unscopedAll bool
+	pk          string    // 主键字段 (由 Repo 指定)，同 `pk` 标签
+	now         time.Time // 自动填充的时间 (执行时指定，以便执行成功后回填)
+	err         error     // 查询选项错误，执行时返回
+}
 
-	// join
-	if len(w.joins) != 0 {
-		for _, cond := range w.joins {
-			builder.WriteString(" ")
-			builder.WriteString(cond.keyword)
-			builder.WriteString(" JOIN ")
-			builder.WriteString(cond.table)
-			if len(cond.query) != 0 {
-				builder.WriteString(" ON ")
-				builder.WriteString(cond.query)
+func newSQLWrapper(tx TXBuilder, dialect Dialect, scopes map[string][]*sqlScope, opts []SQLOption) *sqlWrapper {
+	wrapper := &sqlWrapper{
+		tx:      tx,
+		dialect: dialect,
+	}
+	for _, f := range opts {
+		f(wrapper)
+	}
+	wrapper.applyScopes(scopes)
+	return wrapper
+}
+
+// SoftDeleteScope 软删除作用域名称
+const SoftDeleteScope = "soft_delete"
+
+type sqlScope struct {
+	name string
+	opts []SQLOption
+}
+
+// applyScopes 应用表的全局作用域
+func (w *sqlWrapper) applyScopes(scopes map[string][]*sqlScope) {
+	fields := strings.Fields(w.table)
+	if len(fields) == 0 || w.unscopedAll {
+		return
+	}
+
+	list := make([]*sqlScope, 0, len(scopes[fields[0]]))
+	for _, v := range scopes[fields[0]] {
+		if !SliceIn(w.unscoped, v.name) {
+			list = append(list, v)
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+
+	// 已有 `OR` 条件时先合并为一组，避免作用域条件改变其优先级
+	w.where = groupOr(w.where)
+
+	for _, scope := range list {
+		for _, f := range scope.opts {
+			f(w)
+		}
+	}
+}
+
+// softDelete 软删除作用域
+func softDelete(column string) SQLOption {
+	return func(w *sqlWrapper) {
+		// 使用表名 (或别名) 限定字段，避免 `JOIN` 时字段不明确
+		name := column
+		if fields := strings.Fields(w.table); len(fields) != 0 {
+			name = fields[len(fields)-1] + "." + column
+		}
+		w.where = append(w.where, &SQLClause{
+			keyword: "AND",
+			query:   quoteColumn(w.dialect, name) + " IS NULL",
+		})
+		w.softDelete = column
+	}
+}
+
+type sqlUpsert struct {
+	columns []string
+	data    any
+	nothing bool
+}
+
+// readContext 加锁查询 (如：`FOR UPDATE`) 需在主库执行
+func (w *sqlWrapper) readContext(ctx context.Context) context.Context {
+	if len(w.lock) != 0 {
+		return ForcePrimary(ctx)
+	}
+	return ctx
+}
+
+func (w *sqlWrapper) One(ctx context.Context, dest any) error {
+	query, args, err := w.querySQL()
+	if err != nil {
+		return err
+	}
+	return w.tx.one(w.readContext(ctx), dest, query, args...)
+}
+
+func (w *sqlWrapper) All(ctx context.Context, dest any) error {
+	query, args, err := w.querySQL()
+	if err != nil {
+		return err
+	}
+	return w.tx.all(w.readContext(ctx), dest, query, args...)
+}
+
+func (w *sqlWrapper) Insert(ctx context.Context, data any) (sql.Result, error) {
+	w.now = time.Now()
+
+	query, args, err := w.insertSQL(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := w.tx.exec(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	fillAutoTime(data, w.now, false)
+
+	return ret, nil
+}
+
+func (w *sqlWrapper) BatchInsert(ctx context.Context, data any) (sql.Result, error) {
+	w.now = time.Now()
+
+	query, args, err := w.batchInsertSQL(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := w.tx.exec(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	fillAutoTime(data, w.now, false)
+
+	return ret, nil
+}
+
+func (w *sqlWrapper) InsertFrom(ctx context.Context, columns []string, sub SQLWrapper) (sql.Result, error) {
+	query, args, err := w.insertFromSQL(columns, sub)
+	if err != nil {
+		return nil, err
+	}
+	return w.tx.exec(ctx, query, args...)
+}
+
+func (w *sqlWrapper) ChunkInsert(ctx context.Context, data any, size int) (int64, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.now = time.Now()
+
+	columns, args, err := w.batchInsertValues(data)
+	if err != nil {
+		return 0, err
+	}
+
+	step := len(args) // 每批参数数量
+	if l := len(columns); l != 0 {
+		rows := len(args) / l
+		if size <= 0 || size > rows {
+			size = rows
+		}
+		if limit := w.dialect.MaxParams(); limit > 0 {
+			// `ON CONFLICT` 的更新参数
+			if w.upsert != nil {
+				binds, err := w.upsertSQL(new(strings.Builder), columns)
+				if err != nil {
+					return 0, err
+				}
+				limit -= len(binds)
 			}
+			if n := limit / l; n < size {
+				size = n
+			}
+		}
+		if size < 1 {
+			return 0, fmt.Errorf("too many columns for dialect: %s", w.dialect.Name())
 		}
+		step = size * l
 	}
 
-	// where
-	if len(w.where) != 0 {
-		builder.WriteString(" WHERE (")
-		builder.WriteString(w.where[0].query)
+	var affected int64
+
+	fn := func(ctx context.Context, tx TXBuilder) error {
+		affected = 0
+		for i := 0; i < len(args); i += step {
+			query, binds, err := w.batchInsertQuery(columns, args[i:min(i+step, len(args))])
+			if err != nil {
+				return err
+			}
+			ret, err := tx.exec(ctx, query, binds...)
+			if err != nil {
+				return err
+			}
+			n, err := ret.RowsAffected()
+			if err != nil {
+				return err
+			}
+			affected += n
+		}
+		return nil
+	}
+
+	if _, ok := w.tx.(*txBuilder); ok || step >= len(args) {
+		err = fn(ctx, w.tx)
+	} else {
+		err = w.tx.Transaction(ctx, fn)
+	}
+	if err != nil {
+		return 0, err
+	}
+	fillAutoTime(data, w.now, false)
+
+	return affected, nil
+}
+
+func (w *sqlWrapper) Update(ctx context.Context, data any) (sql.Result, error) {
+	return w.update(ctx, data)
+}
+
+func (w *sqlWrapper) UpdateFields(ctx context.Context, data any, fields ...string) (sql.Result, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("err empty update fields")
+	}
+	return w.update(ctx, data, fields...)
+}
+
+func (w *sqlWrapper) update(ctx context.Context, data any, fields ...string) (sql.Result, error) {
+	w.now = time.Now()
+
+	query, args, err := w.updateSQL(data, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := w.tx.exec(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// 乐观锁
+	if version, ok := versionField(data); ok {
+		n, err := ret.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, ErrStaleVersion
+		}
+		if version.CanSet() && version.CanInt() {
+			version.SetInt(version.Int() + 1)
+		}
+	}
+	fillAutoTime(data, w.now, true)
+
+	return ret, nil
+}
+
+func (w *sqlWrapper) Delete(ctx context.Context) (sql.Result, error) {
+	query, args, err := w.deleteSQL()
+	if err != nil {
+		return nil, err
+	}
+	return w.tx.exec(ctx, query, args...)
+}
+
+func (w *sqlWrapper) Truncate(ctx context.Context) (sql.Result, error) {
+	return w.tx.exec(ctx, w.truncateSQL())
+}
+
+func (w *sqlWrapper) Count(ctx context.Context) (int64, error) {
+	query, args, err := w.countSQL()
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	if err = w.tx.one(ctx, &total, query, args...); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (w *sqlWrapper) Exists(ctx context.Context) (bool, error) {
+	query, args, err := w.existsSQL()
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+	if err = w.tx.one(ctx, &ok, query, args...); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+func (w *sqlWrapper) Pluck(ctx context.Context, column string, dest any) error {
+	query, args, err := w.pluckSQL(column)
+	if err != nil {
+		return err
+	}
+	return w.tx.all(w.readContext(ctx), dest, query, args...)
+}
+
+func (w *sqlWrapper) Paginate(ctx context.Context, page, size int, dest any) (int64, error) {
+	query, args, err := w.paginateSQL(page, size)
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := w.Count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, nil
+	}
+
+	if err = w.tx.all(w.readContext(ctx), dest, query, args...); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (w *sqlWrapper) Keyset(ctx context.Context, keys []string, cursor string, size int, dest any) (string, error) {
+	query, args, err := w.keysetSQL(keys, cursor, size)
+	if err != nil {
+		return "", err
+	}
+	if err = w.tx.all(w.readContext(ctx), dest, query, args...); err != nil {
+		return "", err
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(dest))
+	if v.Kind() != reflect.Slice {
+		return "", ErrSQLBatchDataType
+	}
+	if l := v.Len(); l == 0 || l < size {
+		return "", nil
+	}
+
+	last := reflect.Indirect(v.Index(v.Len() - 1))
+	values := make([]any, 0, len(keys))
+	for _, key := range keys {
+		column, _ := parseKeysetKey(key)
+		value, ok := columnValue(last, column)
+		if !ok {
+			return "", fmt.Errorf("keyset column not found in dest: %s", column)
+		}
+		values = append(values, value)
+	}
+	return encodeCursor(values)
+}
+
+func (w *sqlWrapper) Rows(ctx context.Context) (*sqlx.Rows, error) {
+	query, args, err := w.querySQL()
+	if err != nil {
+		return nil, err
+	}
+	return w.tx.query(w.readContext(ctx), query, args...)
+}
+
+func (w *sqlWrapper) Explain(ctx context.Context) ([]X, error) {
+	query, args, err := w.querySQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := w.tx.query(ctx, w.dialect.Explain(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := make([]X, 0)
+	for rows.Next() {
+		plan := make(map[string]any)
+		if err = rows.MapScan(plan); err != nil {
+			return nil, err
+		}
+		for k, v := range plan {
+			if b, ok := v.([]byte); ok {
+				plan[k] = string(b)
+			}
+		}
+		plans = append(plans, plan)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+func (w *sqlWrapper) ToQuery() (string, []any, error) {
+	return w.rebind(w.querySQL())
+}
+
+func (w *sqlWrapper) ToInsert(data any) (string, []any, error) {
+	return w.rebind(w.insertSQL(data))
+}
+
+func (w *sqlWrapper) ToBatchInsert(data any) (string, []any, error) {
+	return w.rebind(w.batchInsertSQL(data))
+}
+
+func (w *sqlWrapper) ToInsertFrom(columns []string, sub SQLWrapper) (string, []any, error) {
+	return w.rebind(w.insertFromSQL(columns, sub))
+}
+
+func (w *sqlWrapper) ToUpdate(data any) (string, []any, error) {
+	return w.rebind(w.updateSQL(data))
+}
+
+func (w *sqlWrapper) ToDelete() (string, []any, error) {
+	return w.rebind(w.deleteSQL())
+}
+
+func (w *sqlWrapper) ToTruncate() string {
+	return w.truncateSQL()
+}
+
+// rebind 将占位符 `?` 转换为方言对应的类型
+func (w *sqlWrapper) rebind(query string, args []any, err error) (string, []any, error) {
+	if err != nil {
+		return "", nil, err
+	}
+	return sqlx.Rebind(w.dialect.BindType(), query), args, nil
+}
+
+func (w *sqlWrapper) querySQL() (sql string, args []any, err error) {
+	if w.err != nil {
+		err = w.err
+		return
+	}
+
+	sql, args = w.selectSQL()
+
+	// where in
+	if w.whereIn {
+		sql, args, err = sqlx.In(sql, args...)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// selectSQL 生成完整的 `SELECT` 语句（包含 `UNION`），不展开 `IN` 子句
+func (w *sqlWrapper) selectSQL() (string, []any) {
+	if len(w.ctes) == 0 && len(w.unions) == 0 {
+		return w.subquery()
+	}
+
+	var builder strings.Builder
+
+	args := make([]any, 0)
+
+	// with
+	if len(w.ctes) != 0 {
+		args = append(args, w.writeWith(&builder)...)
+	}
+
+	sql, binds := w.subquery()
+	args = append(args, binds...)
+
+	// unions
+	if len(w.unions) == 0 {
+		builder.WriteString(sql)
+		return builder.String(), args
+	}
+
+	builder.WriteString("(")
+	builder.WriteString(sql)
+	builder.WriteString(")")
+
+	for _, v := range w.unions {
+		builder.WriteString(" ")
+		builder.WriteString(v.keyword)
+		builder.WriteString(" (")
+		builder.WriteString(v.query)
 		builder.WriteString(")")
-		args = append(args, w.where[0].binds...)
-		for _, cond := range w.where[1:] {
-			builder.WriteString(" AND (")
+
+		args = append(args, v.binds...)
+	}
+
+	return builder.String(), args
+}
+
+// writeJoins 写入 `JOIN` 子句
+func (w *sqlWrapper) writeJoins(builder *strings.Builder, joins []*SQLClause) []any {
+	args := make([]any, 0)
+	for _, cond := range joins {
+		builder.WriteString(" ")
+		builder.WriteString(cond.keyword)
+		builder.WriteString(" JOIN ")
+		builder.WriteString(quoteColumn(w.dialect, cond.table))
+		if len(cond.query) != 0 {
+			builder.WriteString(" ON ")
 			builder.WriteString(cond.query)
-			builder.WriteString(")")
-			args = append(args, cond.binds...)
 		}
+		args = append(args, cond.binds...)
+	}
+	return args
+}
+
+// joinFrom 将 `JOIN` 转换为 `UPDATE ... FROM` 和 `DELETE ... USING` 的关联表 (Postgres, SQLite)；
+// 关联表以逗号分隔，`ON` 条件并入 `WHERE` (`FROM` 中的 `ON` 条件不能引用目标表)，
+// 因此仅支持 `INNER JOIN` 和 `CROSS JOIN`
+func (w *sqlWrapper) joinFrom(builder *strings.Builder, keyword string) (args []any, where []*SQLClause, err error) {
+	for _, v := range w.joins {
+		if v.keyword != "INNER" && v.keyword != "CROSS" {
+			err = fmt.Errorf("%s join is not supported in %s by dialect: %s", strings.ToLower(v.keyword), strings.ToLower(keyword), w.dialect.Name())
+			return
+		}
+	}
+
+	builder.WriteString(" ")
+	builder.WriteString(keyword)
+	builder.WriteString(" ")
+
+	on := make([]*SQLClause, 0, len(w.joins))
+	for i, v := range w.joins {
+		if i != 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(quoteColumn(w.dialect, v.table))
+		args = append(args, v.binds...)
+		if len(v.query) != 0 {
+			on = append(on, &SQLClause{keyword: "AND", query: v.query})
+		}
+	}
+
+	where = w.where
+	if len(on) != 0 {
+		where = append(on, groupOr(w.where)...)
+	}
+	return
+}
+
+// writeWith 写入 `WITH` 子句
+func (w *sqlWrapper) writeWith(builder *strings.Builder) []any {
+	args := make([]any, 0)
+
+	builder.WriteString("WITH ")
+	if w.recursive {
+		builder.WriteString("RECURSIVE ")
+	}
+	for i, cte := range w.ctes {
+		if i != 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(quoteColumn(w.dialect, cte.table))
+		builder.WriteString(" AS (")
+		builder.WriteString(cte.query)
+		builder.WriteString(")")
+		args = append(args, cte.binds...)
+	}
+	builder.WriteString(" ")
+
+	return args
+}
+
+func (w *sqlWrapper) subquery() (string, []any) {
+	args := make([]any, 0)
+
+	var builder strings.Builder
+
+	builder.WriteString("SELECT ")
+	if w.distinct {
+		builder.WriteString("DISTINCT ")
+	}
+
+	// columns
+	if len(w.columns) == 0 {
+		builder.WriteString("*")
+	} else {
+		builder.WriteString(quoteColumn(w.dialect, w.columns[0]))
+		for _, column := range w.columns[1:] {
+			builder.WriteString(", ")
+			builder.WriteString(quoteColumn(w.dialect, column))
+		}
+	}
+	args = append(args, w.columnBinds...)
+
+	// from
+	builder.WriteString(" FROM ")
+	builder.WriteString(quoteColumn(w.dialect, w.table))
+	args = append(args, w.tableBinds...)
+
+	// join
+	if len(w.joins) != 0 {
+		args = append(args, w.writeJoins(&builder, w.joins)...)
+	}
+
+	// where
+	if len(w.where) != 0 {
+		builder.WriteString(" WHERE ")
+		args = append(args, writeConditions(&builder, w.where)...)
 	}
 
 	// group by
 	if len(w.groups) != 0 {
 		builder.WriteString(" GROUP BY ")
-		builder.WriteString(w.groups[0])
+		builder.WriteString(quoteColumn(w.dialect, w.groups[0]))
 		for _, column := range w.groups[1:] {
 			builder.WriteString(", ")
-			builder.WriteString(column)
+			builder.WriteString(quoteColumn(w.dialect, column))
 		}
 	}
 
 	// having
 	if len(w.having) != 0 {
-		builder.WriteString(" HAVING (")
-		builder.WriteString(w.having[0].query)
-		builder.WriteString(")")
-		args = append(args, w.having[0].binds...)
-		for _, cond := range w.having[1:] {
-			builder.WriteString(" AND (")
-			builder.WriteString(cond.query)
-			builder.WriteString(")")
-			args = append(args, cond.binds...)
-		}
+		builder.WriteString(" HAVING ")
+		args = append(args, writeConditions(&builder, w.having)...)
 	}
 
 	// order by
 	if len(w.orders) != 0 {
 		builder.WriteString(" ORDER BY ")
-		builder.WriteString(w.orders[0])
+		builder.WriteString(quoteOrder(w.dialect, w.orders[0]))
 		for _, column := range w.orders[1:] {
 			builder.WriteString(", ")
-			builder.WriteString(column)
+			builder.WriteString(quoteOrder(w.dialect, column))
+		}
+	}
+
+	// limit & offset
+	if query, binds := w.dialect.Limit(w.limit, w.offset); len(query) != 0 {
+		builder.WriteString(" ")
+		builder.WriteString(query)
+		args = append(args, binds...)
+	}
+
+	// lock
+	if len(w.lock) != 0 {
+		builder.WriteString(" ")
+		builder.WriteString(w.lock)
+	}
+
+	return builder.String(), args
+}
+
+func (w *sqlWrapper) countSQL() (string, []any, error) {
+	v := w.unordered()
+	if v.aggregated() {
+		v = v.derived("t")
+	}
+	v.columns = []string{"COUNT(*)"}
+	v.columnBinds = nil
+	v.distinct = false
+
+	return v.querySQL()
+}
+
+func (w *sqlWrapper) existsSQL() (string, []any, error) {
+	v := w.unordered()
+	if !v.aggregated() {
+		v.columns = []string{"1"}
+		v.columnBinds = nil
+		v.distinct = false
+	}
+
+	query, args, err := v.querySQL()
+	if err != nil {
+		return "", nil, err
+	}
+	return "SELECT EXISTS (" + query + ")", args, nil
+}
+
+func (w *sqlWrapper) pluckSQL(column string) (string, []any, error) {
+	v := w.clone()
+	if len(v.unions) != 0 {
+		// 从 `UNION` 结果集中提取
+		v = w.unordered().derived("t")
+		v.orders = w.orders
+		v.limit = w.limit
+		v.offset = w.offset
+	}
+	v.columns = []string{column}
+	v.columnBinds = nil
+
+	return v.querySQL()
+}
+
+func (w *sqlWrapper) paginateSQL(page, size int) (string, []any, error) {
+	if size < 1 {
+		return "", nil, ErrSQLPageSize
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	v := w.clone()
+	if len(v.unions) != 0 {
+		// 对 `UNION` 结果集分页
+		v = w.unordered().derived("t")
+		v.orders = w.orders
+	}
+	v.limit = size
+	v.offset = (page - 1) * size
+
+	return v.querySQL()
+}
+
+func (w *sqlWrapper) keysetSQL(keys []string, cursor string, size int) (string, []any, error) {
+	if len(keys) == 0 {
+		return "", nil, errors.New("err empty keyset columns")
+	}
+	if size < 1 {
+		return "", nil, ErrSQLPageSize
+	}
+
+	v := w.clone()
+	if len(v.unions) != 0 {
+		v = w.unordered().derived("t")
+	}
+
+	columns := make([]string, 0, len(keys))
+	desc := make([]bool, 0, len(keys))
+	orders := make([]string, 0, len(keys))
+	for _, key := range keys {
+		column, isDesc := parseKeysetKey(key)
+		columns = append(columns, column)
+		desc = append(desc, isDesc)
+		if isDesc {
+			orders = append(orders, column+" DESC")
+		} else {
+			orders = append(orders, column+" ASC")
 		}
 	}
 
-	// limit
-	if w.limit > 0 {
-		builder.WriteString(" LIMIT ?")
-		args = append(args, w.limit)
+	if len(cursor) != 0 {
+		values, err := decodeCursor(cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(values) != len(keys) {
+			return "", nil, ErrSQLCursor
+		}
+		query, binds := keysetCondition(v.dialect, columns, desc, values)
+		// 已有 `OR` 条件时先合并为一组，避免游标条件改变其优先级
+		v.where = append(slices.Clip(groupOr(v.where)), &SQLClause{
+			keyword: "AND",
+			query:   query,
+			binds:   binds,
+		})
 	}
 
-	// offset
-	if w.offset > 0 {
-		builder.WriteString(" OFFSET ?")
-		args = append(args, w.offset)
+	v.orders = orders
+	v.limit = size
+	v.offset = 0
+
+	return v.querySQL()
+}
+
+// keysetCondition 生成游标条件；
+// 排序方向一致时 Postgres 使用元组比较 (a, b) > (?, ?)，否则展开为 (a > ?) OR (a = ? AND b > ?)
+func keysetCondition(d Dialect, columns []string, desc []bool, values []any) (string, []any) {
+	var builder strings.Builder
+
+	quoted := make([]string, 0, len(columns))
+	for _, v := range columns {
+		quoted = append(quoted, quoteColumn(d, v))
+	}
+	columns = quoted
+
+	mixed := false
+	for _, v := range desc[1:] {
+		if v != desc[0] {
+			mixed = true
+			break
+		}
+	}
+
+	op := func(isDesc bool) string {
+		if isDesc {
+			return " < "
+		}
+		return " > "
+	}
+
+	if len(columns) == 1 {
+		builder.WriteString(columns[0])
+		builder.WriteString(op(desc[0]))
+		builder.WriteString("?")
+		return builder.String(), values
+	}
+
+	if !mixed && d.Name() == dialectPostgres {
+		builder.WriteString("(")
+		builder.WriteString(strings.Join(columns, ", "))
+		builder.WriteString(")")
+		builder.WriteString(op(desc[0]))
+		builder.WriteString("(?")
+		builder.WriteString(strings.Repeat(", ?", len(columns)-1))
+		builder.WriteString(")")
+		return builder.String(), values
 	}
 
+	args := make([]any, 0, len(columns)*(len(columns)+1)/2)
+	for i := range columns {
+		if i != 0 {
+			builder.WriteString(" OR ")
+		}
+		builder.WriteString("(")
+		for j := 0; j < i; j++ {
+			builder.WriteString(columns[j])
+			builder.WriteString(" = ? AND ")
+			args = append(args, values[j])
+		}
+		builder.WriteString(columns[i])
+		builder.WriteString(op(desc[i]))
+		builder.WriteString("?)")
+		args = append(args, values[i])
+	}
 	return builder.String(), args
 }
 
+func parseKeysetKey(key string) (string, bool) {
+	if strings.HasPrefix(key, "-") {
+		return key[1:], true
+	}
+	return strings.TrimPrefix(key, "+"), false
+}
+
+// columnValue 获取结构体或 `yiigo.X` 中指定字段的值 (忽略表名前缀)
+func columnValue(v reflect.Value, column string) (any, bool) {
+	if i := strings.LastIndex(column, "."); i != -1 {
+		column = column[i+1:]
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		value := v.MapIndex(reflect.ValueOf(column).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return nil, false
+		}
+		return value.Interface(), true
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			fieldT := t.Field(i)
+			tag := fieldT.Tag.Get("db")
+			if tag == "-" {
+				continue
+			}
+			// 嵌套结构体
+			if fieldT.Anonymous && len(tag) == 0 {
+				if value, ok := columnValue(reflect.Indirect(v.Field(i)), column); ok {
+					return value, true
+				}
+				continue
+			}
+			name, _ := parseTag(tag)
+			if len(name) == 0 {
+				name = strings.ToLower(fieldT.Name)
+			}
+			if name == column {
+				return v.Field(i).Interface(), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// encodeCursor 编码游标；时间类型编码为 {"$time": RFC3339Nano}
+func encodeCursor(values []any) (string, error) {
+	data := make([]any, 0, len(values))
+	for _, v := range values {
+		if valuer, ok := v.(driver.Valuer); ok {
+			dv, err := valuer.Value()
+			if err != nil {
+				return "", err
+			}
+			v = dv
+		}
+		switch t := v.(type) {
+		case time.Time:
+			v = map[string]string{"$time": t.Format(time.RFC3339Nano)}
+		case *time.Time:
+			if t != nil {
+				v = map[string]string{"$time": t.Format(time.RFC3339Nano)}
+			}
+		case []byte:
+			v = string(t)
+		}
+		data = append(data, v)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(cursor string) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrSQLCursor
+	}
+
+	var data []any
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err = decoder.Decode(&data); err != nil {
+		return nil, ErrSQLCursor
+	}
+
+	for i, v := range data {
+		switch t := v.(type) {
+		case json.Number:
+			if n, err := t.Int64(); err == nil {
+				data[i] = n
+			} else if f, err := t.Float64(); err == nil {
+				data[i] = f
+			} else {
+				return nil, ErrSQLCursor
+			}
+		case map[string]any:
+			s, ok := t["$time"].(string)
+			if !ok {
+				return nil, ErrSQLCursor
+			}
+			tm, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, ErrSQLCursor
+			}
+			data[i] = tm
+		case []any:
+			return nil, ErrSQLCursor
+		}
+	}
+	return data, nil
+}
+
+func (w *sqlWrapper) clone() *sqlWrapper {
+	v := *w
+	return &v
+}
+
+// unordered 返回去除 `ORDER BY`, `LIMIT`, `OFFSET` 和行锁的副本
+func (w *sqlWrapper) unordered() *sqlWrapper {
+	v := w.clone()
+	v.orders = nil
+	v.limit = 0
+	v.offset = 0
+	v.lock = ""
+	return v
+}
+
+// aggregated 判断结果集是否经过聚合或合并 (`DISTINCT`, `GROUP BY`, `HAVING`, `UNION`)
+func (w *sqlWrapper) aggregated() bool {
+	return w.distinct || len(w.groups) != 0 || len(w.having) != 0 || len(w.unions) != 0
+}
+
+// derived 返回以当前查询为派生表的查询
+func (w *sqlWrapper) derived(alias string) *sqlWrapper {
+	v := &sqlWrapper{
+		tx:      w.tx,
+		dialect: w.dialect,
+	}
+	TableSub(w, alias)(v)
+	return v
+}
+
 func (w *sqlWrapper) insertSQL(data any) (sql string, args []any, err error) {
+	if w.err != nil {
+		err = w.err
+		return
+	}
+
 	var columns []string
 
 	v := reflect.Indirect(reflect.ValueOf(data))
@@ -418,37 +1851,127 @@ func (w *sqlWrapper) insertSQL(data any) (sql string, args []any, err error) {
 	var builder strings.Builder
 
 	builder.WriteString("INSERT INTO ")
-	builder.WriteString(w.table)
+	builder.WriteString(quoteColumn(w.dialect, w.table))
+
+	if l := len(columns); l != 0 {
+		builder.WriteString(" (")
+		builder.WriteString(quoteColumn(w.dialect, columns[0]))
+		for _, column := range columns[1:] {
+			builder.WriteString(", ")
+			builder.WriteString(quoteColumn(w.dialect, column))
+		}
+
+		builder.WriteString(") VALUES (?")
+		for i := 1; i < l; i++ {
+			builder.WriteString(", ?")
+		}
+		builder.WriteString(")")
+	}
+
+	// on conflict
+	if w.upsert != nil {
+		binds, _err := w.upsertSQL(&builder, columns)
+		if _err != nil {
+			err = _err
+			return
+		}
+		args = append(args, binds...)
+	}
+
+	if err = w.writeReturning(&builder); err != nil {
+		return
+	}
+
+	sql = builder.String()
+
+	return
+}
+
+// insertFromSQL 生成 `INSERT INTO ... SELECT` 语句
+func (w *sqlWrapper) insertFromSQL(columns []string, sub SQLWrapper) (sql string, args []any, err error) {
+	if w.err != nil {
+		err = w.err
+		return
+	}
+
+	v, ok := sub.(*sqlWrapper)
+	if !ok {
+		err = ErrSQLDataType
+		return
+	}
+	if v.err != nil {
+		err = v.err
+		return
+	}
+	// SQLite 的 `INSERT ... SELECT ... ON CONFLICT` 需包含 `WHERE` 子句，以避免解析歧义
+	if w.upsert != nil && w.dialect.Name() == dialectSQLite {
+		v = v.derived("t")
+		v.where = []*SQLClause{{keyword: "AND", query: "true"}}
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString("INSERT INTO ")
+	builder.WriteString(quoteColumn(w.dialect, w.table))
 
-	if l := len(columns); l != 0 {
+	if len(columns) != 0 {
 		builder.WriteString(" (")
-		builder.WriteString(columns[0])
+		builder.WriteString(quoteColumn(w.dialect, columns[0]))
 		for _, column := range columns[1:] {
 			builder.WriteString(", ")
-			builder.WriteString(column)
-		}
-
-		builder.WriteString(") VALUES (?")
-		for i := 1; i < l; i++ {
-			builder.WriteString(", ?")
+			builder.WriteString(quoteColumn(w.dialect, column))
 		}
 		builder.WriteString(")")
 	}
 
-	if len(w.returning) != 0 {
-		builder.WriteString(" RETURNING ")
-		builder.WriteString(w.returning[0])
-		for _, column := range w.returning[1:] {
-			builder.WriteString(", ")
-			builder.WriteString(column)
+	query, binds := v.selectSQL()
+	builder.WriteString(" ")
+	builder.WriteString(query)
+	args = binds
+
+	// on conflict
+	if w.upsert != nil {
+		binds, _err := w.upsertSQL(&builder, columns)
+		if _err != nil {
+			err = _err
+			return
 		}
+		args = append(args, binds...)
+	}
+
+	if err = w.writeReturning(&builder); err != nil {
+		return
 	}
 
 	sql = builder.String()
 
+	if v.whereIn {
+		sql, args, err = sqlx.In(sql, args...)
+		if err != nil {
+			return
+		}
+	}
+
 	return
 }
 
+// writeReturning 写入 `RETURNING` 子句
+func (w *sqlWrapper) writeReturning(builder *strings.Builder) error {
+	if len(w.returning) == 0 {
+		return nil
+	}
+	if !w.dialect.Returning() {
+		return fmt.Errorf("returning is not supported by dialect: %s", w.dialect.Name())
+	}
+	builder.WriteString(" RETURNING ")
+	builder.WriteString(quoteColumn(w.dialect, w.returning[0]))
+	for _, column := range w.returning[1:] {
+		builder.WriteString(", ")
+		builder.WriteString(quoteColumn(w.dialect, column))
+	}
+	return nil
+}
+
 func (w *sqlWrapper) insertWithMap(data X) (columns []string, args []any) {
 	fieldNum := len(data)
 
@@ -468,6 +1991,7 @@ func (w *sqlWrapper) insertWithStruct(v reflect.Value) (columns []string, args [
 	args = make([]any, 0, fieldNum)
 
 	t := v.Type()
+	now := w.timestamp()
 
 	for i := 0; i < fieldNum; i++ {
 		fieldT := t.Field(i)
@@ -477,13 +2001,18 @@ func (w *sqlWrapper) insertWithStruct(v reflect.Value) (columns []string, args [
 		}
 
 		fieldV := v.Field(i)
-		column := fieldT.Name
+		column := strings.ToLower(fieldT.Name) // 与 sqlx 默认的字段映射一致
+
+		var opts tagOptions
 		if len(tag) != 0 {
-			name, opts := parseTag(tag)
-			if opts.Contains("omitempty") && isEmptyValue(fieldV) {
-				continue
+			column, opts = parseTag(tag)
+			if (opts.Contains("autoCreateTime") || opts.Contains("autoUpdateTime")) && fieldV.IsZero() {
+				fieldV = autoTime(fieldV, now)
 			}
-			column = name
+		}
+		// 主键为零值时由数据库生成 (如：自增)
+		if (opts.Contains("omitempty") || w.isPK(column, opts)) && isEmptyValue(fieldV) {
+			continue
 		}
 		columns = append(columns, column)
 		args = append(args, fieldV.Interface())
@@ -492,7 +2021,20 @@ func (w *sqlWrapper) insertWithStruct(v reflect.Value) (columns []string, args [
 	return
 }
 
-func (w *sqlWrapper) batchInsertSQL(data any) (sql string, args []any, err error) {
+func (w *sqlWrapper) batchInsertSQL(data any) (string, []any, error) {
+	if w.err != nil {
+		return "", nil, w.err
+	}
+
+	columns, args, err := w.batchInsertValues(data)
+	if err != nil {
+		return "", nil, err
+	}
+	return w.batchInsertQuery(columns, args)
+}
+
+// batchInsertValues 返回批量插入的字段和按行展开的参数
+func (w *sqlWrapper) batchInsertValues(data any) (columns []string, args []any, err error) {
 	v := reflect.Indirect(reflect.ValueOf(data))
 	if v.Kind() != reflect.Slice {
 		err = ErrSQLBatchDataType
@@ -503,8 +2045,6 @@ func (w *sqlWrapper) batchInsertSQL(data any) (sql string, args []any, err error
 		return
 	}
 
-	var columns []string
-
 	e := v.Type().Elem()
 	switch e.Kind() {
 	case reflect.Map:
@@ -515,30 +2055,33 @@ func (w *sqlWrapper) batchInsertSQL(data any) (sql string, args []any, err error
 		}
 		columns, args = w.batchInsertWithMap(x)
 	case reflect.Struct:
-		columns, args = w.batchInsertWithStruct(v)
+		columns, args, err = w.batchInsertWithStruct(v)
 	case reflect.Ptr:
 		if e.Elem().Kind() != reflect.Struct {
 			err = ErrSQLBatchDataType
 			return
 		}
-		columns, args = w.batchInsertWithStruct(v)
+		columns, args, err = w.batchInsertWithStruct(v)
 	default:
 		err = ErrSQLBatchDataType
-		return
 	}
+	return
+}
 
+// batchInsertQuery 生成批量插入语句，行数为 len(args) / len(columns)
+func (w *sqlWrapper) batchInsertQuery(columns []string, args []any) (string, []any, error) {
 	var builder strings.Builder
 
 	builder.WriteString("INSERT INTO ")
-	builder.WriteString(w.table)
+	builder.WriteString(quoteColumn(w.dialect, w.table))
 
 	if l := len(columns); l != 0 {
 		builder.WriteString(" (")
-		builder.WriteString(columns[0])
+		builder.WriteString(quoteColumn(w.dialect, columns[0]))
 
 		for _, column := range columns[1:] {
 			builder.WriteString(", ")
-			builder.WriteString(column)
+			builder.WriteString(quoteColumn(w.dialect, column))
 		}
 
 		// 首行
@@ -560,9 +2103,17 @@ func (w *sqlWrapper) batchInsertSQL(data any) (sql string, args []any, err error
 		}
 	}
 
-	sql = builder.String()
+	// on conflict
+	if w.upsert != nil {
+		binds, err := w.upsertSQL(&builder, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		// 避免覆盖分批插入时共享的参数
+		args = append(slices.Clip(args), binds...)
+	}
 
-	return
+	return builder.String(), args, nil
 }
 
 func (w *sqlWrapper) batchInsertWithMap(data []X) (columns []string, args []any) {
@@ -585,7 +2136,9 @@ func (w *sqlWrapper) batchInsertWithMap(data []X) (columns []string, args []any)
 	return
 }
 
-func (w *sqlWrapper) batchInsertWithStruct(v reflect.Value) (columns []string, args []any) {
+// batchInsertWithStruct 返回批量插入的字段和参数；
+// 插入字段由首行决定 (`omitempty` 和 `pk` 的零值字段不插入)，其余行按相同字段取值，`pk` 是否为零值需与首行一致
+func (w *sqlWrapper) batchInsertWithStruct(v reflect.Value) (columns []string, args []any, err error) {
 	first := reflect.Indirect(v.Index(0))
 
 	dataLen := v.Len()
@@ -595,43 +2148,200 @@ func (w *sqlWrapper) batchInsertWithStruct(v reflect.Value) (columns []string, a
 	args = make([]any, 0, fieldNum*dataLen)
 
 	t := first.Type()
+	now := w.timestamp()
 
-	for i := 0; i < dataLen; i++ {
-		for j := 0; j < fieldNum; j++ {
-			fieldT := t.Field(j)
+	type field struct {
+		index int
+		auto  bool // autoCreateTime, autoUpdateTime
+		pk    bool
+	}
 
-			tag := fieldT.Tag.Get("db")
-			if tag == "-" {
+	fields := make([]field, 0, fieldNum)
+	pkOmitted := make(map[int]bool)
+
+	for j := 0; j < fieldNum; j++ {
+		fieldT := t.Field(j)
+
+		tag := fieldT.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+
+		f := field{index: j}
+		column := strings.ToLower(fieldT.Name)
+
+		if len(tag) != 0 {
+			name, opts := parseTag(tag)
+
+			f.auto = opts.Contains("autoCreateTime") || opts.Contains("autoUpdateTime")
+			f.pk = opts.Contains("pk")
+
+			fieldV := first.Field(j)
+			if f.auto && fieldV.IsZero() {
+				fieldV = autoTime(fieldV, now)
+			}
+			if opts.Contains("omitempty") && isEmptyValue(fieldV) {
+				continue
+			}
+			if f.pk && isEmptyValue(fieldV) {
+				pkOmitted[j] = true
 				continue
 			}
 
-			fieldV := reflect.Indirect(v.Index(i)).Field(j)
-			column := fieldT.Name
+			column = name
+		}
 
-			if len(tag) != 0 {
-				name, opts := parseTag(tag)
-				if opts.Contains("omitempty") && isEmptyValue(fieldV) {
-					continue
-				}
+		columns = append(columns, column)
+		fields = append(fields, f)
+	}
 
-				column = name
+	for i := 0; i < dataLen; i++ {
+		row := reflect.Indirect(v.Index(i))
+		for j := range pkOmitted {
+			if !isEmptyValue(row.Field(j)) {
+				err = fmt.Errorf("inconsistent pk field %s in batch data", t.Field(j).Name)
+				return
 			}
+		}
+		for _, f := range fields {
+			fieldV := row.Field(f.index)
+			if f.auto && fieldV.IsZero() {
+				fieldV = autoTime(fieldV, now)
+			}
+			if f.pk && isEmptyValue(fieldV) {
+				err = fmt.Errorf("inconsistent pk field %s in batch data", t.Field(f.index).Name)
+				return
+			}
+			args = append(args, fieldV.Interface())
+		}
+	}
+
+	return
+}
+
+func (w *sqlWrapper) upsertSQL(builder *strings.Builder, insertColumns []string) (args []any, err error) {
+	var (
+		columns  []string
+		exprs    map[string]string
+		clause   *SQLClause
+		excluded bool // 是否更新为插入值
+	)
 
-			if i == 0 {
-				columns = append(columns, column)
+	nothing := w.upsert.nothing
+	if !nothing {
+		switch data := w.upsert.data.(type) {
+		case nil:
+			columns = make([]string, 0, len(insertColumns))
+			for _, v := range insertColumns {
+				if !SliceIn(w.upsert.columns, v) {
+					columns = append(columns, v)
+				}
+			}
+			// 无可更新的字段，等同于忽略
+			if len(columns) == 0 {
+				nothing = true
+			}
+			excluded = true
+		case []string:
+			columns = data
+			excluded = true
+		case X:
+			columns, exprs, args, err = w.updateWithMap(data)
+			if err != nil {
+				return
+			}
+		case *SQLClause:
+			clause, err = data.resolve(w.dialect)
+			if err != nil {
+				return
 			}
+			args = clause.binds
+		default:
+			v := reflect.Indirect(reflect.ValueOf(data))
+			if v.Kind() != reflect.Struct {
+				err = ErrSQLDataType
+				return
+			}
+			columns, exprs, args, _ = w.updateWithStruct(v, nil)
+		}
+	}
 
-			args = append(args, fieldV.Interface())
+	var excludedFmt string
+
+	switch w.dialect.Name() {
+	case dialectMySQL:
+		builder.WriteString(" ON DUPLICATE KEY UPDATE ")
+		if nothing {
+			// MySQL 不支持 `DO NOTHING`，使用 `col = col` 实现忽略
+			column := ""
+			if len(w.upsert.columns) != 0 {
+				column = w.upsert.columns[0]
+			} else if len(insertColumns) != 0 {
+				column = insertColumns[0]
+			}
+			column = quoteColumn(w.dialect, column)
+			builder.WriteString(column)
+			builder.WriteString(" = ")
+			builder.WriteString(column)
+			return
+		}
+		excludedFmt = "VALUES(%s)"
+	case dialectPostgres, dialectSQLite:
+		builder.WriteString(" ON CONFLICT")
+		if len(w.upsert.columns) != 0 {
+			builder.WriteString(" (")
+			builder.WriteString(quoteColumn(w.dialect, w.upsert.columns[0]))
+			for _, column := range w.upsert.columns[1:] {
+				builder.WriteString(", ")
+				builder.WriteString(quoteColumn(w.dialect, column))
+			}
+			builder.WriteString(")")
+		}
+		if nothing {
+			builder.WriteString(" DO NOTHING")
+			return
+		}
+		if len(w.upsert.columns) == 0 {
+			err = errors.New("err empty conflict columns")
+			return
+		}
+		builder.WriteString(" DO UPDATE SET ")
+		excludedFmt = "EXCLUDED.%s"
+	default:
+		err = fmt.Errorf("upsert is not supported by dialect: %s", w.dialect.Name())
+		return
+	}
+
+	if clause != nil {
+		builder.WriteString(clause.query)
+		return
+	}
+	if len(columns) == 0 {
+		err = errors.New("err empty update data")
+		return
+	}
+	if excluded {
+		exprs = make(map[string]string, len(columns))
+		for _, v := range columns {
+			exprs[v] = fmt.Sprintf(excludedFmt, quoteColumn(w.dialect, v))
 		}
 	}
+	writeSetColumns(builder, w.dialect, columns, exprs)
 
 	return
 }
 
-func (w *sqlWrapper) updateSQL(data any) (sql string, args []any, err error) {
+// updateSQL 生成更新语句，fields 不为空时仅更新指定字段 (仅支持结构体)
+func (w *sqlWrapper) updateSQL(data any, fields ...string) (sql string, args []any, err error) {
+	if w.err != nil {
+		err = w.err
+		return
+	}
+
 	var (
 		columns []string
 		exprs   map[string]string
+		version *SQLClause
 	)
 
 	v := reflect.Indirect(reflect.ValueOf(data))
@@ -639,62 +2349,82 @@ func (w *sqlWrapper) updateSQL(data any) (sql string, args []any, err error) {
 	switch v.Kind() {
 	case reflect.Map:
 		x, ok := data.(X)
-		if !ok {
+		if !ok || len(fields) != 0 {
 			err = ErrSQLDataType
 			return
 		}
 
-		columns, exprs, args = w.updateWithMap(x)
+		columns, exprs, args, err = w.updateWithMap(x)
+		if err != nil {
+			return
+		}
 	case reflect.Struct:
-		columns, args = w.updateWithStruct(v)
+		columns, exprs, args, version = w.updateWithStruct(v, fields)
+		for _, field := range fields {
+			if !SliceIn(columns, field) {
+				err = fmt.Errorf("invalid update field: %s", field)
+				return
+			}
+		}
 	default:
 		err = ErrSQLDataType
 		return
 	}
 
-	var builder strings.Builder
+	var (
+		builder strings.Builder
+		binds   []any
+	)
+
+	// with
+	if len(w.ctes) != 0 {
+		binds = append(binds, w.writeWith(&builder)...)
+	}
 
 	builder.WriteString("UPDATE ")
-	builder.WriteString(w.table)
+	builder.WriteString(quoteColumn(w.dialect, w.table))
+
+	// MySQL: UPDATE ... JOIN ... SET
+	joinFrom := len(w.joins) != 0
+	if joinFrom {
+		switch w.dialect.Name() {
+		case dialectMySQL:
+			binds = append(binds, w.writeJoins(&builder, w.joins)...)
+			joinFrom = false
+		case dialectPostgres, dialectSQLite:
+		default:
+			err = fmt.Errorf("update with join is not supported by dialect: %s", w.dialect.Name())
+			return
+		}
+	}
 
 	if len(columns) != 0 {
 		builder.WriteString(" SET ")
-		builder.WriteString(columns[0])
-
-		if expr, ok := exprs[columns[0]]; ok {
-			builder.WriteString(" = ")
-			builder.WriteString(expr)
-		} else {
-			builder.WriteString(" = ?")
-		}
-
-		for _, column := range columns[1:] {
-			builder.WriteString(", ")
-			builder.WriteString(column)
-
-			if expr, ok := exprs[column]; ok {
-				builder.WriteString(" = ")
-				builder.WriteString(expr)
-			} else {
-				builder.WriteString(" = ?")
-			}
-		}
+		writeSetColumns(&builder, w.dialect, columns, exprs)
 	}
+	binds = append(binds, args...)
 
-	if len(w.where) != 0 {
-		builder.WriteString(" WHERE (")
-		builder.WriteString(w.where[0].query)
-		builder.WriteString(")")
-		args = append(args, w.where[0].binds...)
-		for _, cond := range w.where[1:] {
-			builder.WriteString(" AND (")
-			builder.WriteString(cond.query)
-			builder.WriteString(")")
-			args = append(args, cond.binds...)
+	where := w.where
+	// Postgres, SQLite: UPDATE ... SET ... FROM
+	if joinFrom {
+		joinBinds, joinWhere, _err := w.joinFrom(&builder, "FROM")
+		if _err != nil {
+			err = _err
+			return
 		}
+		binds = append(binds, joinBinds...)
+		where = joinWhere
+	}
+	if version != nil {
+		where = append(slices.Clip(groupOr(where)), version)
+	}
+	if len(where) != 0 {
+		builder.WriteString(" WHERE ")
+		binds = append(binds, writeConditions(&builder, where)...)
 	}
 
 	sql = builder.String()
+	args = binds
 
 	if w.whereIn {
 		sql, args, err = sqlx.In(sql, args...)
@@ -706,7 +2436,7 @@ func (w *sqlWrapper) updateSQL(data any) (sql string, args []any, err error) {
 	return
 }
 
-func (w *sqlWrapper) updateWithMap(data X) (columns []string, exprs map[string]string, args []any) {
+func (w *sqlWrapper) updateWithMap(data X) (columns []string, exprs map[string]string, args []any, err error) {
 	fieldNum := len(data)
 
 	columns = make([]string, 0, fieldNum)
@@ -717,72 +2447,295 @@ func (w *sqlWrapper) updateWithMap(data X) (columns []string, exprs map[string]s
 		columns = append(columns, k)
 
 		if clause, ok := v.(*SQLClause); ok {
-			exprs[k] = clause.query
-			args = append(args, clause.binds...)
+			expr, e := clause.resolve(w.dialect)
+			if e != nil {
+				err = e
+				return
+			}
+			exprs[k] = expr.query
+			args = append(args, expr.binds...)
 
 			continue
 		}
 
 		args = append(args, v)
 	}
-
-	return
+
+	return
+}
+
+// updateWithStruct 返回更新字段和参数，version 为乐观锁条件；
+// fields 不为空时仅更新指定字段 (忽略 `omitempty`)，`autoUpdateTime` 和 `version` 字段总是更新
+func (w *sqlWrapper) updateWithStruct(v reflect.Value, fields []string) (columns []string, exprs map[string]string, args []any, version *SQLClause) {
+	fieldNum := v.NumField()
+
+	columns = make([]string, 0, fieldNum)
+	exprs = make(map[string]string)
+	args = make([]any, 0, fieldNum)
+
+	t := v.Type()
+	now := w.timestamp()
+
+	for i := 0; i < fieldNum; i++ {
+		fieldT := t.Field(i)
+
+		tag := fieldT.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+
+		fieldV := v.Field(i)
+		column := strings.ToLower(fieldT.Name)
+		auto := false
+
+		if len(tag) == 0 && w.isPK(column, "") {
+			continue
+		}
+		if len(tag) != 0 {
+			name, opts := parseTag(tag)
+			switch {
+			case w.isPK(name, opts), opts.Contains("autoCreateTime"):
+				continue
+			case opts.Contains("autoUpdateTime"):
+				fieldV = autoTime(fieldV, now)
+				auto = true
+			case opts.Contains("version"):
+				quoted := quoteColumn(w.dialect, name)
+				columns = append(columns, name)
+				exprs[name] = quoted + " + 1"
+				version = &SQLClause{
+					keyword: "AND",
+					query:   quoted + " = ?",
+					binds:   []any{fieldV.Interface()},
+				}
+				continue
+			}
+			if len(fields) == 0 && opts.Contains("omitempty") && isEmptyValue(fieldV) {
+				continue
+			}
+
+			column = name
+		}
+
+		if len(fields) != 0 && !auto && !SliceIn(fields, column) {
+			continue
+		}
+
+		columns = append(columns, column)
+		args = append(args, fieldV.Interface())
+	}
+
+	return
+}
+
+// isPK 判断字段是否为主键 (标记 `pk` 或由 Repo 指定)
+func (w *sqlWrapper) isPK(column string, opts tagOptions) bool {
+	return opts.Contains("pk") || (len(w.pk) != 0 && column == w.pk)
+}
+
+// timestamp 返回自动填充的时间，执行时为指定的时间，生成SQL (如：`ToInsert`) 时为当前时间
+func (w *sqlWrapper) timestamp() time.Time {
+	if w.now.IsZero() {
+		return time.Now()
+	}
+	return w.now
+}
+
+// autoTime 返回自动填充的时间 (支持：`time.Time`, `*time.Time` 和整型的Unix时间戳)，不修改原字段
+func autoTime(fieldV reflect.Value, now time.Time) reflect.Value {
+	var value reflect.Value
+
+	switch fieldV.Interface().(type) {
+	case time.Time:
+		value = reflect.ValueOf(now)
+	case *time.Time:
+		value = reflect.ValueOf(&now)
+	default:
+		if !fieldV.CanInt() {
+			return fieldV
+		}
+		value = reflect.ValueOf(now.Unix()).Convert(fieldV.Type())
+	}
+	return value
+}
+
+// fillAutoTime 执行成功后回填结构体 (`*struct`, `[]struct`, `[]*struct`) 中自动填充的时间字段；
+// 插入时回填零值的 `autoCreateTime` 和 `autoUpdateTime` 字段，更新时回填 `autoUpdateTime` 字段
+func fillAutoTime(data any, now time.Time, update bool) {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	switch v.Kind() {
+	case reflect.Struct:
+		fillStructTime(v, now, update)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			fillStructTime(reflect.Indirect(v.Index(i)), now, update)
+		}
+	}
+}
+
+func fillStructTime(v reflect.Value, now time.Time, update bool) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fieldV := v.Field(i)
+		if !fieldV.CanSet() {
+			continue
+		}
+
+		_, opts := parseTag(t.Field(i).Tag.Get("db"))
+		if opts.Contains("pk") {
+			continue
+		}
+		if update {
+			if opts.Contains("autoCreateTime") || !opts.Contains("autoUpdateTime") {
+				continue
+			}
+		} else if !(opts.Contains("autoCreateTime") || opts.Contains("autoUpdateTime")) || !fieldV.IsZero() {
+			continue
+		}
+		if value := autoTime(fieldV, now); value.Type() == fieldV.Type() {
+			fieldV.Set(value)
+		}
+	}
 }
 
-func (w *sqlWrapper) updateWithStruct(v reflect.Value) (columns []string, args []any) {
-	fieldNum := v.NumField()
-
-	columns = make([]string, 0, fieldNum)
-	args = make([]any, 0, fieldNum)
+// SQLDiff 比较结构体修改前后的值，返回变更的字段名 (db 标签名)，用于 `UpdateFields`；
+// 忽略 `pk`, `version`, `autoCreateTime` 和 `autoUpdateTime` 字段，例如：
+//
+//	old := *user
+//	user.Name = "yiigo"
+//	fields, _ := yiigo.SQLDiff(&old, user)
+//	builder.Wrap(...).UpdateFields(ctx, user, fields...)
+func SQLDiff(old, new any) ([]string, error) {
+	ov := reflect.Indirect(reflect.ValueOf(old))
+	nv := reflect.Indirect(reflect.ValueOf(new))
+	if ov.Kind() != reflect.Struct || ov.Type() != nv.Type() {
+		return nil, ErrSQLDataType
+	}
 
-	t := v.Type()
+	t := ov.Type()
 
-	for i := 0; i < fieldNum; i++ {
+	fields := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
 		fieldT := t.Field(i)
 
 		tag := fieldT.Tag.Get("db")
-		if tag == "-" {
+		if tag == "-" || !fieldT.IsExported() {
 			continue
 		}
 
-		fieldV := v.Field(i)
-		column := fieldT.Name
-
+		column := strings.ToLower(fieldT.Name)
 		if len(tag) != 0 {
 			name, opts := parseTag(tag)
-			if opts.Contains("omitempty") && isEmptyValue(fieldV) {
+			if opts.Contains("pk") || opts.Contains("version") || opts.Contains("autoCreateTime") || opts.Contains("autoUpdateTime") {
 				continue
 			}
-
 			column = name
 		}
 
-		columns = append(columns, column)
-		args = append(args, fieldV.Interface())
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			fields = append(fields, column)
+		}
 	}
+	return fields, nil
+}
 
-	return
+// versionField 返回结构体中标记 `version` 的字段
+func versionField(data any) (reflect.Value, bool) {
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if _, opts := parseTag(t.Field(i).Tag.Get("db")); opts.Contains("version") {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
 }
 
 func (w *sqlWrapper) deleteSQL() (sql string, args []any, err error) {
+	if w.err != nil {
+		err = w.err
+		return
+	}
+
+	// 软删除
+	if len(w.softDelete) != 0 {
+		column := w.softDelete
+		// MySQL 多表更新时限定字段，避免字段不明确
+		if len(w.joins) != 0 && w.dialect.Name() == dialectMySQL {
+			column = w.target() + "." + column
+		}
+		return w.updateSQL(X{column: SQLExpr("CURRENT_TIMESTAMP")})
+	}
+
 	var builder strings.Builder
 
-	builder.WriteString("DELETE FROM ")
-	builder.WriteString(w.table)
+	// with
+	if len(w.ctes) != 0 {
+		args = append(args, w.writeWith(&builder)...)
+	}
+
+	where := w.where
 
-	if len(w.where) != 0 {
-		builder.WriteString(" WHERE (")
-		builder.WriteString(w.where[0].query)
-		builder.WriteString(")")
-		args = append(args, w.where[0].binds...)
-		for _, cond := range w.where[1:] {
-			builder.WriteString(" AND (")
-			builder.WriteString(cond.query)
+	if len(w.joins) == 0 {
+		builder.WriteString("DELETE FROM ")
+		builder.WriteString(quoteColumn(w.dialect, w.table))
+	} else {
+		switch w.dialect.Name() {
+		case dialectMySQL:
+			// DELETE t FROM t JOIN ...
+			builder.WriteString("DELETE ")
+			builder.WriteString(quoteColumn(w.dialect, w.target()))
+			builder.WriteString(" FROM ")
+			builder.WriteString(quoteColumn(w.dialect, w.table))
+			args = append(args, w.writeJoins(&builder, w.joins)...)
+		case dialectPostgres:
+			// DELETE FROM t USING ...
+			builder.WriteString("DELETE FROM ")
+			builder.WriteString(quoteColumn(w.dialect, w.table))
+
+			binds, joinWhere, _err := w.joinFrom(&builder, "USING")
+			if _err != nil {
+				err = _err
+				return
+			}
+			args = append(args, binds...)
+			where = joinWhere
+		case dialectSQLite:
+			// SQLite 不支持 `DELETE ... USING`，使用 rowid 子查询
+			v := w.unordered()
+			v.columns = []string{w.target() + ".rowid"}
+			v.columnBinds = nil
+			v.ctes = nil
+			v.unions = nil
+
+			query, binds := v.subquery()
+
+			builder.WriteString("DELETE FROM ")
+			builder.WriteString(quoteColumn(w.dialect, strings.Fields(w.table)[0]))
+			builder.WriteString(" WHERE rowid IN (")
+			builder.WriteString(query)
 			builder.WriteString(")")
-			args = append(args, cond.binds...)
+			args = append(args, binds...)
+			where = nil
+		default:
+			err = fmt.Errorf("delete with join is not supported by dialect: %s", w.dialect.Name())
+			return
 		}
 	}
 
+	if len(where) != 0 {
+		builder.WriteString(" WHERE ")
+		args = append(args, writeConditions(&builder, where)...)
+	}
+
 	sql = builder.String()
 
 	if w.whereIn {
@@ -795,29 +2748,138 @@ func (w *sqlWrapper) deleteSQL() (sql string, args []any, err error) {
 	return
 }
 
-func (w *sqlWrapper) truncateSQL() string {
-	var builder strings.Builder
-
-	builder.WriteString("TRUNCATE ")
-	builder.WriteString(w.table)
+// target 返回主表的别名 (未指定时为表名)
+func (w *sqlWrapper) target() string {
+	fields := strings.Fields(w.table)
+	if len(fields) == 0 {
+		return w.table
+	}
+	return fields[len(fields)-1]
+}
 
-	return builder.String()
+func (w *sqlWrapper) truncateSQL() string {
+	return w.dialect.Truncate(quoteColumn(w.dialect, w.table))
 }
 
 // SQLOption SQL查询选项
 type SQLOption func(w *sqlWrapper)
 
-// Table 指定查询表名称
+// With 指定 `WITH` 子句（公用表表达式），作用于 `SELECT`, `UPDATE` 和 `DELETE` 语句；
+// 名称可包含字段，例如：yiigo.With("t(id, name)", builder.Wrap(...))
+func With(name string, sub SQLWrapper) SQLOption {
+	return func(w *sqlWrapper) {
+		v, ok := sub.(*sqlWrapper)
+		if !ok {
+			return
+		}
+		if v.err != nil {
+			w.err = v.err
+			return
+		}
+		query, binds := v.selectSQL()
+		w.ctes = append(w.ctes, &SQLClause{
+			table: name,
+			query: query,
+			binds: binds,
+		})
+		w.whereIn = w.whereIn || v.whereIn
+	}
+}
+
+// WithRecursive 指定 `WITH RECURSIVE` 子句（递归公用表表达式），
+// 通常使用 `UnionAll` 连接初始查询和递归查询
+func WithRecursive(name string, sub SQLWrapper) SQLOption {
+	return func(w *sqlWrapper) {
+		With(name, sub)(w)
+		w.recursive = true
+	}
+}
+
+// Unscoped 忽略指定名称的全局作用域，不指定则忽略全部 (如：查询已软删除的数据、物理删除)
+func Unscoped(names ...string) SQLOption {
+	return func(w *sqlWrapper) {
+		if len(names) == 0 {
+			w.unscopedAll = true
+		}
+		w.unscoped = append(w.unscoped, names...)
+	}
+}
+
+// Table 指定查询表名称 (标识符会根据方言添加引号)
 func Table(name string) SQLOption {
 	return func(w *sqlWrapper) {
 		w.table = name
 	}
 }
 
-// Select 指定查询字段名
+// TableSub 指定子查询作为查询表（派生表），例如：SELECT * FROM (SELECT ...) AS t
+func TableSub(sub SQLWrapper, alias string) SQLOption {
+	return func(w *sqlWrapper) {
+		v, ok := sub.(*sqlWrapper)
+		if !ok {
+			return
+		}
+		if v.err != nil {
+			w.err = v.err
+			return
+		}
+		query, binds := v.selectSQL()
+		w.table = "(" + query + ") AS " + alias
+		w.tableBinds = binds
+		w.whereIn = w.whereIn || v.whereIn
+	}
+}
+
+// Select 指定查询字段名；字段名 (含别名) 会根据方言添加引号，表达式原样输出；
+// 带参数的表达式 (如：窗口函数) 使用 `SelectExpr`
 func Select(columns ...string) SQLOption {
 	return func(w *sqlWrapper) {
 		w.columns = columns
+		w.columnBinds = nil
+	}
+}
+
+// SelectSub 指定子查询作为查询字段，例如：(SELECT COUNT(*) FROM address WHERE address.user_id = user.id) AS total
+func SelectSub(sub SQLWrapper, alias string) SQLOption {
+	return func(w *sqlWrapper) {
+		v, ok := sub.(*sqlWrapper)
+		if !ok {
+			return
+		}
+		if v.err != nil {
+			w.err = v.err
+			return
+		}
+		query, binds := v.selectSQL()
+		w.columns = append(w.columns, "("+query+") AS "+alias)
+		w.columnBinds = append(w.columnBinds, binds...)
+		w.whereIn = w.whereIn || v.whereIn
+	}
+}
+
+// SelectExpr 追加查询字段，支持字段名和 `yiigo.SQLExpr`（表达式），表达式的参数按位置合并，例如：
+//
+//	yiigo.SelectExpr("id", yiigo.JSONExtract("attrs", "color").As("color"))
+//	yiigo.SelectExpr("id", yiigo.SQLRowNumber().Over(yiigo.SQLWindow{OrderBy: []string{"age DESC"}}).As("rn"))
+func SelectExpr(columns ...any) SQLOption {
+	return func(w *sqlWrapper) {
+		for _, column := range columns {
+			switch v := column.(type) {
+			case string:
+				w.columns = append(slices.Clip(w.columns), v)
+			case *SQLClause:
+				expr, err := v.resolve(w.dialect)
+				if err != nil {
+					w.err = err
+					return
+				}
+				w.columns = append(slices.Clip(w.columns), expr.query)
+				w.columnBinds = append(w.columnBinds, expr.binds...)
+			default:
+				w.err = ErrSQLDataType
+				return
+			}
+		}
 	}
 }
 
@@ -825,6 +2887,7 @@ func Select(columns ...string) SQLOption {
 func Distinct(columns ...string) SQLOption {
 	return func(w *sqlWrapper) {
 		w.columns = columns
+		w.columnBinds = nil
 		w.distinct = true
 	}
 }
@@ -873,6 +2936,42 @@ func FullJoin(table, on string) SQLOption {
 	}
 }
 
+// JoinSub 指定子查询的 `INNER JOIN` 子句
+func JoinSub(sub SQLWrapper, alias, on string) SQLOption {
+	return joinSub("INNER", sub, alias, on)
+}
+
+// LeftJoinSub 指定子查询的 `LEFT JOIN` 子句
+func LeftJoinSub(sub SQLWrapper, alias, on string) SQLOption {
+	return joinSub("LEFT", sub, alias, on)
+}
+
+// RightJoinSub 指定子查询的 `RIGHT JOIN` 子句
+func RightJoinSub(sub SQLWrapper, alias, on string) SQLOption {
+	return joinSub("RIGHT", sub, alias, on)
+}
+
+func joinSub(keyword string, sub SQLWrapper, alias, on string) SQLOption {
+	return func(w *sqlWrapper) {
+		v, ok := sub.(*sqlWrapper)
+		if !ok {
+			return
+		}
+		if v.err != nil {
+			w.err = v.err
+			return
+		}
+		query, binds := v.selectSQL()
+		w.joins = append(w.joins, &SQLClause{
+			table:   "(" + query + ") AS " + alias,
+			keyword: keyword,
+			query:   on,
+			binds:   binds,
+		})
+		w.whereIn = w.whereIn || v.whereIn
+	}
+}
+
 // CrossJoin 指定 `CROSS JOIN` 语句
 func CrossJoin(table string) SQLOption {
 	return func(w *sqlWrapper) {
@@ -883,24 +2982,64 @@ func CrossJoin(table string) SQLOption {
 	}
 }
 
-// Where 指定 `WHERE` 子句
+// Where 指定 `WHERE` 子句，多个条件之间使用 `AND` 连接；
+// 参数支持 `SQLWrapper`（子查询）和 `yiigo.SQLExpr`（表达式），例如：yiigo.Where("id IN (?)", builder.Wrap(...))
 func Where(query string, binds ...any) SQLOption {
 	return func(w *sqlWrapper) {
-		w.where = append(w.where, &SQLClause{
-			query: query,
-			binds: binds,
-		})
+		if clause := w.condition("AND", query, binds); clause != nil {
+			w.where = append(w.where, clause)
+		}
+	}
+}
+
+// WhereOr 指定 `WHERE` 子句，与前一个条件之间使用 `OR` 连接
+func WhereOr(query string, binds ...any) SQLOption {
+	return func(w *sqlWrapper) {
+		if clause := w.condition("OR", query, binds); clause != nil {
+			w.where = append(w.where, clause)
+		}
 	}
 }
 
 // WhereIn 指定 `IN` 子句
 func WhereIn(query string, binds ...any) SQLOption {
 	return func(w *sqlWrapper) {
-		w.where = append(w.where, &SQLClause{
-			query: query,
-			binds: binds,
-		})
-		w.whereIn = true
+		if clause := w.condition("AND", query, binds); clause != nil {
+			w.where = append(w.where, clause)
+			w.whereIn = true
+		}
+	}
+}
+
+// WhereOrIn 指定 `IN` 子句，与前一个条件之间使用 `OR` 连接
+func WhereOrIn(query string, binds ...any) SQLOption {
+	return func(w *sqlWrapper) {
+		if clause := w.condition("OR", query, binds); clause != nil {
+			w.where = append(w.where, clause)
+			w.whereIn = true
+		}
+	}
+}
+
+// WhereGroup 指定 `WHERE` 嵌套条件组，与前一个条件之间使用 `AND` 连接，例如：
+//
+//	yiigo.WhereGroup(func(c *yiigo.SQLCondition) {
+//		c.Where("age > ?", 20).OrWhere("vip = ?", 1)
+//	})
+func WhereGroup(fn func(c *SQLCondition)) SQLOption {
+	return func(w *sqlWrapper) {
+		if clause := w.conditionGroup("AND", fn); clause != nil {
+			w.where = append(w.where, clause)
+		}
+	}
+}
+
+// WhereOrGroup 指定 `WHERE` 嵌套条件组，与前一个条件之间使用 `OR` 连接
+func WhereOrGroup(fn func(c *SQLCondition)) SQLOption {
+	return func(w *sqlWrapper) {
+		if clause := w.conditionGroup("OR", fn); clause != nil {
+			w.where = append(w.where, clause)
+		}
 	}
 }
 
@@ -911,23 +3050,104 @@ func GroupBy(columns ...string) SQLOption {
 	}
 }
 
-// Having 指定 `HAVING` 子句
+// Having 指定 `HAVING` 子句，多个条件之间使用 `AND` 连接
 func Having(query string, binds ...any) SQLOption {
 	return func(w *sqlWrapper) {
-		w.having = append(w.having, &SQLClause{
-			query: query,
-			binds: binds,
-		})
+		if clause := w.condition("AND", query, binds); clause != nil {
+			w.having = append(w.having, clause)
+		}
+	}
+}
+
+// HavingOr 指定 `HAVING` 子句，与前一个条件之间使用 `OR` 连接
+func HavingOr(query string, binds ...any) SQLOption {
+	return func(w *sqlWrapper) {
+		if clause := w.condition("OR", query, binds); clause != nil {
+			w.having = append(w.having, clause)
+		}
+	}
+}
+
+// HavingGroup 指定 `HAVING` 嵌套条件组，与前一个条件之间使用 `AND` 连接
+func HavingGroup(fn func(c *SQLCondition)) SQLOption {
+	return func(w *sqlWrapper) {
+		if clause := w.conditionGroup("AND", fn); clause != nil {
+			w.having = append(w.having, clause)
+		}
+	}
+}
+
+// HavingOrGroup 指定 `HAVING` 嵌套条件组，与前一个条件之间使用 `OR` 连接
+func HavingOrGroup(fn func(c *SQLCondition)) SQLOption {
+	return func(w *sqlWrapper) {
+		if clause := w.conditionGroup("OR", fn); clause != nil {
+			w.having = append(w.having, clause)
+		}
 	}
 }
 
-// OrderBy 指定 `ORDER BY` 子句
+// OrderBy 指定 `ORDER BY` 子句；字段名会根据方言添加引号，表达式原样输出
 func OrderBy(columns ...string) SQLOption {
 	return func(w *sqlWrapper) {
 		w.orders = columns
 	}
 }
 
+// OrderByParam 根据请求参数指定 `ORDER BY` 子句，例如：sort=-created_at,name；
+// `-` 前缀表示降序，`+` 或无前缀表示升序；allowed 为允许排序的字段 (对外名称 => 实际字段，实际字段为空时同对外名称)，
+// 字段不在其中时返回 `*yiigo.SQLSortFieldError`；参数为空时保持原有排序
+func OrderByParam(input string, allowed map[string]string) SQLOption {
+	return func(w *sqlWrapper) {
+		orders, err := ParseOrderParam(input, allowed)
+		if err != nil {
+			w.err = err
+			return
+		}
+		if len(orders) != 0 {
+			w.orders = orders
+		}
+	}
+}
+
+// ParseOrderParam 解析排序请求参数，返回排序字段，例如：-created_at,name => ["created_at DESC", "name ASC"]
+func ParseOrderParam(input string, allowed map[string]string) ([]string, error) {
+	var (
+		orders []string
+		seen   []string
+	)
+
+	for _, v := range strings.Split(input, ",") {
+		v = strings.TrimSpace(v)
+		if len(v) == 0 {
+			continue
+		}
+
+		direction := "ASC"
+		switch v[0] {
+		case '-':
+			direction = "DESC"
+			v = v[1:]
+		case '+':
+			v = v[1:]
+		}
+
+		column, ok := allowed[v]
+		if !ok || len(v) == 0 {
+			return nil, &SQLSortFieldError{Field: v}
+		}
+		if len(column) == 0 {
+			column = v
+		}
+		if SliceIn(seen, column) {
+			continue
+		}
+		seen = append(seen, column)
+
+		orders = append(orders, column+" "+direction)
+	}
+	return orders, nil
+}
+
 // Offset 指定 `OFFSET` 子句
 func Offset(n int) SQLOption {
 	return func(w *sqlWrapper) {
@@ -942,6 +3162,55 @@ func Limit(n int) SQLOption {
 	}
 }
 
+// SQLLockWait 行锁的等待策略
+type SQLLockWait string
+
+const (
+	LockNoWait     SQLLockWait = "NOWAIT"      // 不等待，行已被锁定时立即报错
+	LockSkipLocked SQLLockWait = "SKIP LOCKED" // 跳过已被锁定的行
+)
+
+// ForUpdate 指定 `FOR UPDATE` 行锁 (需在事务中使用)，例如：yiigo.ForUpdate(yiigo.LockSkipLocked)；
+// SQLite 不支持行锁
+func ForUpdate(wait ...SQLLockWait) SQLOption {
+	return lockOption("UPDATE", wait)
+}
+
+// ForShare 指定 `FOR SHARE` 行锁 (MySQL 8.0+)
+func ForShare(wait ...SQLLockWait) SQLOption {
+	return lockOption("SHARE", wait)
+}
+
+// ForNoKeyUpdate 指定 `FOR NO KEY UPDATE` 行锁 (仅 Postgres)
+func ForNoKeyUpdate(wait ...SQLLockWait) SQLOption {
+	return lockOption("NO KEY UPDATE", wait)
+}
+
+// ForKeyShare 指定 `FOR KEY SHARE` 行锁 (仅 Postgres)
+func ForKeyShare(wait ...SQLLockWait) SQLOption {
+	return lockOption("KEY SHARE", wait)
+}
+
+func lockOption(strength string, wait []SQLLockWait) SQLOption {
+	return func(w *sqlWrapper) {
+		switch w.dialect.Name() {
+		case dialectSQLite:
+			w.err = fmt.Errorf("lock is not supported by dialect: %s", w.dialect.Name())
+			return
+		case dialectMySQL:
+			if strength != "UPDATE" && strength != "SHARE" {
+				w.err = fmt.Errorf("lock for %s is not supported by dialect: %s", strings.ToLower(strength), w.dialect.Name())
+				return
+			}
+		}
+
+		w.lock = "FOR " + strength
+		if len(wait) != 0 && len(wait[0]) != 0 {
+			w.lock += " " + string(wait[0])
+		}
+	}
+}
+
 // Returning 指定 `RETURNING` 子句；
 // 用于 PostgresSQL 和 SQLite(3.35.0) `INSERT` 语句
 func Returning(columns ...string) SQLOption {
@@ -950,6 +3219,29 @@ func Returning(columns ...string) SQLOption {
 	}
 }
 
+// OnConflictUpdate 指定 `INSERT` 冲突时更新（即：upsert）；用于 `Insert` 和 `BatchInsert` 语句
+//   - columns 冲突字段（MySQL 由唯一索引决定，可不指定；Postgres 和 SQLite 必须指定）
+//   - data 更新数据：nil = 更新为插入值（冲突字段除外）, []string = 指定字段更新为插入值,
+//     struct, *struct, yiigo.X = 指定更新值, yiigo.SQLExpr = 指定更新表达式
+func OnConflictUpdate(columns []string, data any) SQLOption {
+	return func(w *sqlWrapper) {
+		w.upsert = &sqlUpsert{
+			columns: columns,
+			data:    data,
+		}
+	}
+}
+
+// OnConflictDoNothing 指定 `INSERT` 冲突时忽略；用于 `Insert` 和 `BatchInsert` 语句
+func OnConflictDoNothing(columns ...string) SQLOption {
+	return func(w *sqlWrapper) {
+		w.upsert = &sqlUpsert{
+			columns: columns,
+			nothing: true,
+		}
+	}
+}
+
 // Union 指定 `UNION` 子句
 func Union(wrappers ...SQLWrapper) SQLOption {
 	return func(w *sqlWrapper) {
@@ -958,6 +3250,10 @@ func Union(wrappers ...SQLWrapper) SQLOption {
 			if !ok {
 				continue
 			}
+			if v.err != nil {
+				w.err = v.err
+				return
+			}
 			if v.whereIn {
 				w.whereIn = true
 			}
@@ -979,6 +3275,10 @@ func UnionAll(wrappers ...SQLWrapper) SQLOption {
 			if !ok {
 				continue
 			}
+			if v.err != nil {
+				w.err = v.err
+				return
+			}
 			if v.whereIn {
 				w.whereIn = true
 			}
@@ -992,6 +3292,78 @@ func UnionAll(wrappers ...SQLWrapper) SQLOption {
 	}
 }
 
+// TxPanicFn 事务回调发生Panic的处理方法
+type TxPanicFn func(ctx context.Context, err any, stack []byte)
+
+func safeTxCallback(ctx context.Context, panicFn TxPanicFn, fn func()) {
+	defer func() {
+		if v := recover(); v != nil && panicFn != nil {
+			panicFn(ctx, v, debug.Stack())
+		}
+	}()
+	fn()
+}
+
+// savepointSQL 返回保存点的创建、回滚和释放语句 (根据方言名称，未知驱动的方言名称即驱动名称)
+func savepointSQL(d Dialect, name string) (savepoint, rollback, release string) {
+	switch d.Name() {
+	case "sqlserver", "mssql", "azuresql":
+		// SQL Server 无需释放保存点
+		return "SAVE TRANSACTION " + name, "ROLLBACK TRANSACTION " + name, ""
+	}
+	return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, "RELEASE SAVEPOINT " + name
+}
+
+func writeConditions(builder *strings.Builder, clauses []*SQLClause) []any {
+	args := make([]any, 0)
+	for i, cond := range clauses {
+		if i != 0 {
+			builder.WriteString(" ")
+			builder.WriteString(cond.keyword)
+			builder.WriteString(" ")
+		}
+		builder.WriteString("(")
+		builder.WriteString(cond.query)
+		builder.WriteString(")")
+		args = append(args, cond.binds...)
+	}
+	return args
+}
+
+// groupOr 条件中包含 `OR` 时合并为一组，以便追加 `AND` 条件
+func groupOr(clauses []*SQLClause) []*SQLClause {
+	if !slices.ContainsFunc(clauses, func(v *SQLClause) bool { return v.keyword == "OR" }) {
+		return clauses
+	}
+
+	var builder strings.Builder
+
+	binds := writeConditions(&builder, clauses)
+
+	return []*SQLClause{
+		{
+			keyword: "AND",
+			query:   builder.String(),
+			binds:   binds,
+		},
+	}
+}
+
+func writeSetColumns(builder *strings.Builder, d Dialect, columns []string, exprs map[string]string) {
+	for i, column := range columns {
+		if i != 0 {
+			builder.WriteString(", ")
+		}
+		builder.WriteString(quoteColumn(d, column))
+		if expr, ok := exprs[column]; ok {
+			builder.WriteString(" = ")
+			builder.WriteString(expr)
+		} else {
+			builder.WriteString(" = ?")
+		}
+	}
+}
+
 // tagOptions is the string following a comma in a struct field's "json"
 // tag, or the empty string. It does not include the leading comma.
 type tagOptions string
@@ -1,13 +1,26 @@
 package yiigo
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 )
 
 func warpper(opts ...SQLOption) *sqlWrapper {
-	wrapper := new(sqlWrapper)
+	wrapper := &sqlWrapper{dialect: NewDialect("")}
+	for _, f := range opts {
+		f(wrapper)
+	}
+	return wrapper
+}
+
+func warpperWithDriver(driver string, opts ...SQLOption) *sqlWrapper {
+	wrapper := &sqlWrapper{dialect: NewDialect(driver)}
 	for _, f := range opts {
 		f(wrapper)
 	}
@@ -185,6 +198,397 @@ func TestToQuery(t *testing.T) {
 	assert.Equal(t, []any{1, 2, 3}, args)
 }
 
+func TestToQueryCondition(t *testing.T) {
+	sql, args, err := warpper(
+		Table("user"),
+		Where("age > ?", 20),
+		WhereOr("vip = ?", 1),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE (age > ?) OR (vip = ?)", sql)
+	assert.Equal(t, []any{20, 1}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("status = ?", 1),
+		WhereGroup(func(c *SQLCondition) {
+			c.Where("age > ?", 20).OrWhere("vip = ?", 1)
+		}),
+		WhereGroup(func(c *SQLCondition) {}),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE (status = ?) AND ((age > ?) OR (vip = ?))", sql)
+	assert.Equal(t, []any{1, 20, 1}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("status = ?", 1),
+		WhereOrGroup(func(c *SQLCondition) {
+			c.WhereIn("age IN (?)", []int{20, 30}).
+				OrGroup(func(c *SQLCondition) {
+					c.Where("vip = ?", 1).Where("name = ?", "yiigo")
+				})
+		}),
+		Where("id > ?", 10),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE (status = ?) OR ((age IN (?, ?)) OR ((vip = ?) AND (name = ?))) AND (id > ?)", sql)
+	assert.Equal(t, []any{1, 20, 30, 1, "yiigo", 10}, args)
+
+	sql, args, err = warpper(
+		Table("address"),
+		Select("user_id", "COUNT(*) AS total"),
+		GroupBy("user_id"),
+		Having("total > ?", 1),
+		HavingOrGroup(func(c *SQLCondition) {
+			c.Where("user_id = ?", 1).OrWhere("user_id = ?", 2)
+		}),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT user_id, COUNT(*) AS total FROM address GROUP BY user_id HAVING (total > ?) OR ((user_id = ?) OR (user_id = ?))", sql)
+	assert.Equal(t, []any{1, 1, 2}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("id = ?", 1),
+		WhereOrIn("id IN (?)", []int{2, 3}),
+	).deleteSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE FROM user WHERE (id = ?) OR (id IN (?, ?))", sql)
+	assert.Equal(t, []any{1, 2, 3}, args)
+}
+
+func TestToQuerySubquery(t *testing.T) {
+	sql, args, err := warpper(
+		Table("user"),
+		Where("age > ?", 20),
+		Where("id IN (?)", warpper(
+			Table("address"),
+			Select("user_id"),
+			Where("city = ?", "shanghai"),
+		)),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE (age > ?) AND (id IN (SELECT user_id FROM address WHERE (city = ?)))", sql)
+	assert.Equal(t, []any{20, "shanghai"}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("EXISTS (?) AND age > ?", warpper(
+			Table("address"),
+			Select("1"),
+			WhereIn("address.user_id = user.id AND city IN (?)", []string{"shanghai", "beijing"}),
+		), 20),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE (EXISTS (SELECT 1 FROM address WHERE (address.user_id = user.id AND city IN (?, ?))) AND age > ?)", sql)
+	assert.Equal(t, []any{"shanghai", "beijing", 20}, args)
+
+	sql, args, err = warpper(
+		Table("product"),
+		Where("price > ?", SQLExpr("cost * ?", 2)),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM product WHERE (price > cost * ?)", sql)
+	assert.Equal(t, []any{2}, args)
+
+	sql, args, err = warpper(
+		TableSub(warpper(
+			Table("order"),
+			Select("user_id", "SUM(amount) AS amount"),
+			Where("status = ?", 1),
+			GroupBy("user_id"),
+		), "t"),
+		Where("t.amount > ?", 100),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM (SELECT user_id, SUM(amount) AS amount FROM order WHERE (status = ?) GROUP BY user_id) AS t WHERE (t.amount > ?)", sql)
+	assert.Equal(t, []any{1, 100}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Select("user.id", "user.name"),
+		SelectSub(warpper(
+			Table("address"),
+			Select("COUNT(*)"),
+			Where("address.user_id = user.id AND address.status = ?", 1),
+		), "address_count"),
+		LeftJoinSub(warpper(
+			Table("order"),
+			Select("user_id", "SUM(amount) AS amount"),
+			Where("status = ?", 2),
+			GroupBy("user_id"),
+		), "o", "o.user_id = user.id"),
+		Where("user.age > ?", 20),
+		Limit(10),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT user.id, user.name, (SELECT COUNT(*) FROM address WHERE (address.user_id = user.id AND address.status = ?)) AS address_count FROM user LEFT JOIN (SELECT user_id, SUM(amount) AS amount FROM order WHERE (status = ?) GROUP BY user_id) AS o ON o.user_id = user.id WHERE (user.age > ?) LIMIT ?", sql)
+	assert.Equal(t, []any{1, 2, 20, 10}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("id IN (?)", warpper(Table("blacklist"), Select("user_id"), Where("type = ?", 1))),
+	).deleteSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE FROM user WHERE (id IN (SELECT user_id FROM blacklist WHERE (type = ?)))", sql)
+	assert.Equal(t, []any{1}, args)
+
+	// 子查询的错误需返回
+	var fieldErr *SQLSortFieldError
+
+	invalid := func() SQLWrapper {
+		return warpper(Table("order"), Select("user_id"), OrderByParam("unknown", nil))
+	}
+	for _, opt := range []SQLOption{
+		Where("id IN (?)", invalid()),
+		TableSub(invalid(), "t"),
+		SelectSub(invalid(), "o"),
+		LeftJoinSub(invalid(), "o", "o.user_id = user.id"),
+		With("o", invalid()),
+		Union(invalid()),
+		UnionAll(invalid()),
+	} {
+		_, _, err = warpper(Table("user"), opt).querySQL()
+		assert.ErrorAs(t, err, &fieldErr)
+	}
+}
+
+func TestToQueryWith(t *testing.T) {
+	sql, args, err := warpper(
+		With("vip", warpper(
+			Table("user"),
+			Select("id", "name"),
+			Where("level > ?", 3),
+		)),
+		Table("vip"),
+		Where("name LIKE ?", "yiigo%"),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "WITH vip AS (SELECT id, name FROM user WHERE (level > ?)) SELECT * FROM vip WHERE (name LIKE ?)", sql)
+	assert.Equal(t, []any{3, "yiigo%"}, args)
+
+	sql, args, err = warpper(
+		WithRecursive("tree", warpper(
+			Table("category"),
+			Where("id = ?", 1),
+			UnionAll(warpper(
+				Table("category c"),
+				Select("c.*"),
+				Join("tree t", "c.pid = t.id"),
+				Where("c.status = ?", 1),
+			)),
+		)),
+		With("cnt(total)", warpper(Table("category"), Select("COUNT(*)"))),
+		Table("tree"),
+		OrderBy("id"),
+		Limit(100),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "WITH RECURSIVE tree AS ((SELECT * FROM category WHERE (id = ?)) UNION ALL (SELECT c.* FROM category c INNER JOIN tree t ON c.pid = t.id WHERE (c.status = ?))), cnt(total) AS (SELECT COUNT(*) FROM category) SELECT * FROM tree ORDER BY id LIMIT ?", sql)
+	assert.Equal(t, []any{1, 1, 100}, args)
+
+	sql, args, err = warpper(
+		With("expired", warpper(Table("order"), Select("id"), WhereIn("status IN (?)", []int{1, 2}))),
+		Table("order"),
+		Where("id IN (SELECT id FROM expired)"),
+	).updateSQL(X{"status": 3})
+	assert.Nil(t, err)
+	assert.Equal(t, "WITH expired AS (SELECT id FROM order WHERE (status IN (?, ?))) UPDATE order SET status = ? WHERE (id IN (SELECT id FROM expired))", sql)
+	assert.Equal(t, []any{1, 2, 3}, args)
+
+	sql, args, err = warpper(
+		With("expired", warpper(Table("order"), Select("id"), Where("status = ?", 1))),
+		Table("order"),
+		Where("id IN (SELECT id FROM expired) AND created_at < ?", "2025-01-01"),
+	).deleteSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "WITH expired AS (SELECT id FROM order WHERE (status = ?)) DELETE FROM order WHERE (id IN (SELECT id FROM expired) AND created_at < ?)", sql)
+	assert.Equal(t, []any{1, "2025-01-01"}, args)
+}
+
+func TestToCount(t *testing.T) {
+	sql, args, err := warpper(
+		Table("user"),
+		Select("id", "name"),
+		Where("age > ?", 20),
+		OrderBy("id DESC"),
+		Offset(10),
+		Limit(10),
+	).countSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT COUNT(*) FROM user WHERE (age > ?)", sql)
+	assert.Equal(t, []any{20}, args)
+
+	sql, args, err = warpper(
+		Table("address"),
+		Select("user_id", "COUNT(*) AS total"),
+		GroupBy("user_id"),
+		Having("total > ?", 1),
+		Limit(10),
+	).countSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT user_id, COUNT(*) AS total FROM address GROUP BY user_id HAVING (total > ?)) AS t", sql)
+	assert.Equal(t, []any{1}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Distinct("name"),
+		WhereIn("age IN (?)", []int{20, 30}),
+	).countSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT COUNT(*) FROM (SELECT DISTINCT name FROM user WHERE (age IN (?, ?))) AS t", sql)
+	assert.Equal(t, []any{20, 30}, args)
+
+	sql, args, err = warpper(
+		Table("user_0"),
+		Where("id = ?", 1),
+		Union(warpper(Table("user_1"), Where("id = ?", 2))),
+	).countSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT COUNT(*) FROM ((SELECT * FROM user_0 WHERE (id = ?)) UNION (SELECT * FROM user_1 WHERE (id = ?))) AS t", sql)
+	assert.Equal(t, []any{1, 2}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("age > ?", 20),
+		OrderBy("id DESC"),
+	).existsSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT EXISTS (SELECT 1 FROM user WHERE (age > ?))", sql)
+	assert.Equal(t, []any{20}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Distinct("name"),
+		Where("age > ?", 20),
+		OrderBy("name"),
+	).pluckSQL("name")
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT DISTINCT name FROM user WHERE (age > ?) ORDER BY name", sql)
+	assert.Equal(t, []any{20}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("age > ?", 20),
+		OrderBy("id DESC"),
+	).paginateSQL(3, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE (age > ?) ORDER BY id DESC LIMIT ? OFFSET ?", sql)
+	assert.Equal(t, []any{20, 10, 20}, args)
+
+	sql, args, err = warpper(
+		Table("user_0"),
+		Where("id > ?", 1),
+		OrderBy("id DESC"),
+		UnionAll(warpper(Table("user_1"), Where("id > ?", 2))),
+	).paginateSQL(1, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM ((SELECT * FROM user_0 WHERE (id > ?)) UNION ALL (SELECT * FROM user_1 WHERE (id > ?))) AS t ORDER BY id DESC LIMIT ?", sql)
+	assert.Equal(t, []any{1, 2, 10}, args)
+
+	_, _, err = warpper(Table("user")).paginateSQL(1, 0)
+	assert.ErrorIs(t, err, ErrSQLPageSize)
+}
+
+func TestToKeyset(t *testing.T) {
+	sql, args, err := warpper(
+		Table("user"),
+		Where("age > ?", 20),
+		OrderBy("name"),
+	).keysetSQL([]string{"id"}, "", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE (age > ?) ORDER BY id ASC LIMIT ?", sql)
+	assert.Equal(t, []any{20, 10}, args)
+
+	cursor, err := encodeCursor([]any{int64(100)})
+	assert.Nil(t, err)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("age > ?", 20),
+	).keysetSQL([]string{"-id"}, cursor, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE (age > ?) AND (id < ?) ORDER BY id DESC LIMIT ?", sql)
+	assert.Equal(t, []any{20, int64(100), 10}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("age > ?", 20),
+		WhereOr("vip = ?", 1),
+	).keysetSQL([]string{"-id"}, cursor, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user WHERE ((age > ?) OR (vip = ?)) AND (id < ?) ORDER BY id DESC LIMIT ?", sql)
+	assert.Equal(t, []any{20, 1, int64(100), 10}, args)
+
+	created := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	cursor, err = encodeCursor([]any{created, int64(100)})
+	assert.Nil(t, err)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("user"),
+	).keysetSQL([]string{"created_at", "id"}, cursor, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "user" WHERE (("created_at", "id") > (?, ?)) ORDER BY "created_at" ASC, "id" ASC LIMIT ?`, sql)
+	assert.Equal(t, []any{created, int64(100), 10}, args)
+
+	sql, args, err = warpperWithDriver("mysql",
+		Table("user"),
+	).keysetSQL([]string{"-created_at", "-id"}, cursor, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM `user` WHERE ((`created_at` < ?) OR (`created_at` = ? AND `id` < ?)) ORDER BY `created_at` DESC, `id` DESC LIMIT ?", sql)
+	assert.Equal(t, []any{created, created, int64(100), 10}, args)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("user"),
+	).keysetSQL([]string{"-created_at", "id"}, cursor, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "user" WHERE (("created_at" < ?) OR ("created_at" = ? AND "id" > ?)) ORDER BY "created_at" DESC, "id" ASC LIMIT ?`, sql)
+	assert.Equal(t, []any{created, created, int64(100), 10}, args)
+
+	_, _, err = warpper(Table("user")).keysetSQL([]string{"created_at", "id"}, "invalid", 10)
+	assert.ErrorIs(t, err, ErrSQLCursor)
+
+	_, _, err = warpper(Table("user")).keysetSQL([]string{"id"}, "", 0)
+	assert.ErrorIs(t, err, ErrSQLPageSize)
+}
+
+func TestToOrderByParam(t *testing.T) {
+	allowed := map[string]string{
+		"created_at": "user.created_at",
+		"name":       "",
+	}
+
+	sql, _, err := warpper(
+		Table("user"),
+		OrderByParam("-created_at, name,-name", allowed),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user ORDER BY user.created_at DESC, name ASC", sql)
+
+	sql, _, err = warpper(
+		Table("user"),
+		OrderBy("id DESC"),
+		OrderByParam("", allowed),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM user ORDER BY id DESC", sql)
+
+	_, _, err = warpper(
+		Table("user"),
+		OrderByParam("name,id;DROP TABLE user", allowed),
+	).querySQL()
+	var fieldErr *SQLSortFieldError
+	assert.ErrorAs(t, err, &fieldErr)
+	assert.Equal(t, "id;DROP TABLE user", fieldErr.Field)
+
+	_, _, err = warpper(
+		Table("user"),
+		OrderByParam("-", allowed),
+	).countSQL()
+	assert.ErrorAs(t, err, &fieldErr)
+}
+
 func TestToInsert(t *testing.T) {
 	type User struct {
 		ID     int    `db:"-"`
@@ -299,6 +703,84 @@ func TestToBatchInsert(t *testing.T) {
 	// assert.Equal(t, []any{29, "M", "yiigo", 20, "W", "test"}, args)
 }
 
+func TestToUpsert(t *testing.T) {
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	type Update struct {
+		Name string `db:"name"`
+	}
+
+	sql, args, err := warpperWithDriver("mysql",
+		Table("user"),
+		OnConflictUpdate([]string{"id"}, nil),
+	).insertSQL(&User{ID: 1, Name: "yiigo", Age: 29})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO `user` (`id`, `name`, `age`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `age` = VALUES(`age`)", sql)
+	assert.Equal(t, []any{1, "yiigo", 29}, args)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("user"),
+		OnConflictUpdate([]string{"id"}, nil),
+		Returning("id"),
+	).insertSQL(&User{ID: 1, Name: "yiigo", Age: 29})
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("id", "name", "age") VALUES (?, ?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "age" = EXCLUDED."age" RETURNING "id"`, sql)
+	assert.Equal(t, []any{1, "yiigo", 29}, args)
+
+	sql, args, err = warpperWithDriver("sqlite3",
+		Table("user"),
+		OnConflictUpdate([]string{"id"}, []string{"name"}),
+	).insertSQL(&User{ID: 1, Name: "yiigo", Age: 29})
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("id", "name", "age") VALUES (?, ?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`, sql)
+	assert.Equal(t, []any{1, "yiigo", 29}, args)
+
+	sql, args, err = warpperWithDriver("pgx",
+		Table("user"),
+		OnConflictUpdate([]string{"id"}, &Update{Name: "shenghui0779"}),
+	).insertSQL(&User{ID: 1, Name: "yiigo", Age: 29})
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("id", "name", "age") VALUES (?, ?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = ?`, sql)
+	assert.Equal(t, []any{1, "yiigo", 29, "shenghui0779"}, args)
+
+	sql, args, err = warpperWithDriver("mysql",
+		Table("user"),
+		OnConflictUpdate(nil, SQLExpr("age = age + ?", 1)),
+	).insertSQL(&User{ID: 1, Name: "yiigo", Age: 29})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO `user` (`id`, `name`, `age`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE age = age + ?", sql)
+	assert.Equal(t, []any{1, "yiigo", 29, 1}, args)
+
+	sql, args, err = warpperWithDriver("mysql",
+		Table("user"),
+		OnConflictDoNothing("id"),
+	).insertSQL(&User{ID: 1, Name: "yiigo", Age: 29})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO `user` (`id`, `name`, `age`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `id` = `id`", sql)
+	assert.Equal(t, []any{1, "yiigo", 29}, args)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("user"),
+		OnConflictDoNothing(),
+	).batchInsertSQL([]*User{
+		{ID: 1, Name: "yiigo", Age: 29},
+		{ID: 2, Name: "test", Age: 20},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("id", "name", "age") VALUES (?, ?, ?), (?, ?, ?) ON CONFLICT DO NOTHING`, sql)
+	assert.Equal(t, []any{1, "yiigo", 29, 2, "test", 20}, args)
+
+	_, _, err = warpperWithDriver("postgres",
+		Table("user"),
+		OnConflictUpdate(nil, nil),
+	).insertSQL(&User{ID: 1, Name: "yiigo", Age: 29})
+	assert.NotNil(t, err)
+}
+
 func TestToUpdate(t *testing.T) {
 	type User struct {
 		Name   string `db:"name"`
@@ -390,6 +872,883 @@ func TestToDelete(t *testing.T) {
 	assert.Equal(t, []any{1, 2}, args)
 }
 
-func TestToTruncate(t *testing.T) {
-	assert.Equal(t, "TRUNCATE user", warpper(Table("user")).truncateSQL())
+func TestToInsertFrom(t *testing.T) {
+	sub := warpperWithDriver("postgres",
+		Table("user_old"),
+		Select("name", "age"),
+		WhereIn("id IN (?)", []int{1, 2}),
+	)
+
+	sql, args, err := warpperWithDriver("postgres",
+		Table("user"),
+		Returning("id"),
+	).ToInsertFrom([]string{"name", "age"}, sub)
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("name", "age") SELECT "name", "age" FROM "user_old" WHERE (id IN ($1, $2)) RETURNING "id"`, sql)
+	assert.Equal(t, []any{1, 2}, args)
+
+	sql, args, err = warpperWithDriver("mysql",
+		Table("user"),
+		OnConflictUpdate(nil, []string{"age"}),
+	).insertFromSQL([]string{"name", "age"}, warpperWithDriver("mysql", Table("user_old"), Select("name", "age"), Where("age > ?", 20)))
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO `user` (`name`, `age`) SELECT `name`, `age` FROM `user_old` WHERE (age > ?) ON DUPLICATE KEY UPDATE `age` = VALUES(`age`)", sql)
+	assert.Equal(t, []any{20}, args)
+
+	sql, args, err = warpperWithDriver("sqlite3",
+		Table("user"),
+		OnConflictUpdate([]string{"name"}, []string{"age"}),
+	).insertFromSQL([]string{"name", "age"}, warpperWithDriver("sqlite3", Table("user_old"), Select("name", "age")))
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("name", "age") SELECT * FROM (SELECT "name", "age" FROM "user_old") AS t WHERE (true) ON CONFLICT ("name") DO UPDATE SET "age" = EXCLUDED."age"`, sql)
+	assert.Len(t, args, 0)
+}
+
+func TestToUpdateJoin(t *testing.T) {
+	opts := []SQLOption{
+		Table("user u"),
+		Join("address a", "a.user_id = u.id"),
+		LeftJoin("city c", "c.id = a.city_id"),
+		Where("a.status = ?", 1),
+		WhereOr("u.vip = ?", 1),
+	}
+
+	sql, args, err := warpperWithDriver("mysql", opts...).updateSQL(X{"u.city": SQLExpr("c.name"), "u.age": 20})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(sql, "UPDATE `user` `u` INNER JOIN `address` `a` ON a.user_id = u.id LEFT JOIN `city` `c` ON c.id = a.city_id SET "))
+	assert.True(t, strings.HasSuffix(sql, " WHERE (a.status = ?) OR (u.vip = ?)"))
+	assert.Equal(t, []any{20, 1, 1}, args)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("user u"),
+		Join("address a", "a.user_id = u.id"),
+		Join("city c", "c.id = u.city_id"),
+		Where("a.status = ?", 1),
+		WhereOr("u.vip = ?", 1),
+	).updateSQL(X{"age": 20})
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "user" "u" SET "age" = ? FROM "address" "a", "city" "c" WHERE (a.user_id = u.id) AND (c.id = u.city_id) AND ((a.status = ?) OR (u.vip = ?))`, sql)
+	assert.Equal(t, []any{20, 1, 1}, args)
+
+	// 外连接的 `ON` 条件无法并入 `WHERE`
+	_, _, err = warpperWithDriver("postgres", opts...).updateSQL(X{"age": 20})
+	assert.NotNil(t, err)
+
+	_, _, err = warpperWithDriver("postgres",
+		Table("user u"),
+		LeftJoin("address a", "a.user_id = u.id"),
+	).updateSQL(X{"age": 20})
+	assert.NotNil(t, err)
+
+	_, _, err = warpper(opts...).updateSQL(X{"age": 20})
+	assert.NotNil(t, err)
+}
+
+func TestToDeleteJoin(t *testing.T) {
+	opts := []SQLOption{
+		Table("user u"),
+		Join("address a", "a.user_id = u.id"),
+		Where("a.status = ?", 1),
+	}
+
+	sql, args, err := warpperWithDriver("mysql", opts...).deleteSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE `u` FROM `user` `u` INNER JOIN `address` `a` ON a.user_id = u.id WHERE (a.status = ?)", sql)
+	assert.Equal(t, []any{1}, args)
+
+	sql, args, err = warpperWithDriver("postgres", opts...).deleteSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `DELETE FROM "user" "u" USING "address" "a" WHERE (a.user_id = u.id) AND (a.status = ?)`, sql)
+	assert.Equal(t, []any{1}, args)
+
+	sql, args, err = warpperWithDriver("sqlite3", opts...).deleteSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `DELETE FROM "user" WHERE rowid IN (SELECT "u"."rowid" FROM "user" "u" INNER JOIN "address" "a" ON a.user_id = u.id WHERE (a.status = ?))`, sql)
+	assert.Equal(t, []any{1}, args)
+
+	// 软删除
+	w := warpperWithDriver("mysql", opts...)
+	softDelete("deleted_at")(w)
+	sql, _, err = w.deleteSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE `user` `u` INNER JOIN `address` `a` ON a.user_id = u.id SET `u`.`deleted_at` = CURRENT_TIMESTAMP WHERE (a.status = ?) AND (`u`.`deleted_at` IS NULL)", sql)
+}
+
+func TestSQLJoinWriteSQLite(t *testing.T) {
+	ctx := context.Background()
+
+	db := sqliteDB(t)
+	_, err := db.Exec("CREATE TABLE address (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, city TEXT NOT NULL)")
+	assert.Nil(t, err)
+
+	builder := NewSQLBuilder(db, nil)
+
+	_, err = builder.Wrap(Table("address")).BatchInsert(ctx, []X{
+		{"user_id": 1, "city": "shanghai"},
+		{"user_id": 2, "city": "beijing"},
+	})
+	assert.Nil(t, err)
+
+	// INSERT ... SELECT
+	_, err = builder.Wrap(Table("user")).InsertFrom(ctx, []string{"name", "age"}, builder.Wrap(
+		Table("address"),
+		Select("city", "user_id"),
+		OrderBy("id"),
+	))
+	assert.Nil(t, err)
+
+	// UPDATE ... FROM
+	_, err = builder.Wrap(
+		Table("user"),
+		Join("address a", "a.user_id = user.id"),
+		Where("a.city = ?", "beijing"),
+	).Update(ctx, X{"age": SQLExpr("a.id * ?", 10)})
+	assert.Nil(t, err)
+
+	var ages []int
+	err = builder.Wrap(Table("user"), OrderBy("id")).Pluck(ctx, "age", &ages)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 20}, ages)
+
+	// DELETE ... JOIN
+	ret, err := builder.Wrap(
+		Table("user u"),
+		Join("address a", "a.user_id = u.id"),
+		Where("a.city = ?", "shanghai"),
+	).Delete(ctx)
+	assert.Nil(t, err)
+	n, _ := ret.RowsAffected()
+	assert.Equal(t, int64(1), n)
+
+	var names []string
+	err = builder.Wrap(Table("user")).Pluck(ctx, "name", &names)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"beijing"}, names)
+}
+
+func TestToLock(t *testing.T) {
+	sql, args, err := warpperWithDriver("postgres",
+		Table("job"),
+		Where("status = ?", 0),
+		OrderBy("id"),
+		Limit(10),
+		ForUpdate(LockSkipLocked),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "job" WHERE (status = ?) ORDER BY "id" LIMIT ? FOR UPDATE SKIP LOCKED`, sql)
+	assert.Equal(t, []any{0, 10}, args)
+
+	sql, _, err = warpperWithDriver("postgres",
+		Table("stock"),
+		Where("id = ?", 1),
+		ForNoKeyUpdate(LockNoWait),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "stock" WHERE (id = ?) FOR NO KEY UPDATE NOWAIT`, sql)
+
+	sql, _, err = warpperWithDriver("mysql",
+		Table("stock"),
+		Where("id = ?", 1),
+		ForShare(),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT * FROM `stock` WHERE (id = ?) FOR SHARE", sql)
+
+	// 计数不加锁
+	sql, _, err = warpperWithDriver("mysql",
+		Table("stock"),
+		ForUpdate(),
+	).countSQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT COUNT(*) FROM `stock`", sql)
+
+	_, _, err = warpperWithDriver("mysql", Table("stock"), ForKeyShare()).querySQL()
+	assert.NotNil(t, err)
+
+	_, _, err = warpperWithDriver("sqlite3", Table("stock"), ForUpdate()).querySQL()
+	assert.NotNil(t, err)
+}
+
+func TestToTruncate(t *testing.T) {
+	assert.Equal(t, "TRUNCATE user", warpper(Table("user")).truncateSQL())
+}
+
+func TestSQLWrapperToSQL(t *testing.T) {
+	sql, args, err := warpperWithDriver("postgres",
+		Table("user"),
+		WhereIn("id IN (?)", []int{1, 2}),
+		Where("age > ?", 20),
+	).ToQuery()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "user" WHERE (id IN ($1, $2)) AND (age > $3)`, sql)
+	assert.Equal(t, []any{1, 2, 20}, args)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("user"),
+		Where("id = ?", 1),
+	).ToUpdate(X{"name": "yiigo"})
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "user" SET "name" = $1 WHERE (id = $2)`, sql)
+	assert.Equal(t, []any{"yiigo", 1}, args)
+
+	sql, args, err = warpperWithDriver("mysql",
+		Table("user"),
+		Where("id = ?", 1),
+	).ToDelete()
+	assert.Nil(t, err)
+	assert.Equal(t, "DELETE FROM `user` WHERE (id = ?)", sql)
+	assert.Equal(t, []any{1}, args)
+
+	_, _, err = warpper(Table("user")).ToBatchInsert([]X{})
+	assert.NotNil(t, err)
+}
+
+func sqliteDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	// 内存数据库每个连接独立
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE user (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, age INTEGER NOT NULL DEFAULT 0)")
+	assert.Nil(t, err)
+
+	return db
+}
+
+func sqliteBuilder(t *testing.T, opts ...SQLBuilderOption) SQLBuilder {
+	return NewSQLBuilderWithOptions(sqliteDB(t), opts...)
+}
+
+func TestSQLWrapperPaginate(t *testing.T) {
+	type User struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	ctx := context.Background()
+	builder := sqliteBuilder(t)
+
+	_, err := builder.Wrap(Table("user")).BatchInsert(ctx, []X{
+		{"name": "a", "age": 10},
+		{"name": "b", "age": 20},
+		{"name": "c", "age": 20},
+		{"name": "d", "age": 30},
+		{"name": "e", "age": 40},
+	})
+	assert.Nil(t, err)
+
+	total, err := builder.Wrap(Table("user"), Where("age >= ?", 20), Limit(1)).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), total)
+
+	total, err = builder.Wrap(Table("user"), Distinct("age")).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), total)
+
+	ok, err := builder.Wrap(Table("user"), Where("age > ?", 30)).Exists(ctx)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = builder.Wrap(Table("user"), Where("age > ?", 40)).Exists(ctx)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	var names []string
+	err = builder.Wrap(Table("user"), Where("age = ?", 20), OrderBy("id")).Pluck(ctx, "name", &names)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b", "c"}, names)
+
+	var records []User
+	total, err = builder.Wrap(Table("user"), Where("age >= ?", 20), OrderBy("id")).Paginate(ctx, 2, 3, &records)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Equal(t, []User{{ID: 5, Name: "e", Age: 40}}, records)
+}
+
+func TestSQLWrapperKeyset(t *testing.T) {
+	type User struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	ctx := context.Background()
+	builder := sqliteBuilder(t)
+
+	_, err := builder.Wrap(Table("user")).BatchInsert(ctx, []X{
+		{"name": "a", "age": 20},
+		{"name": "b", "age": 30},
+		{"name": "c", "age": 20},
+		{"name": "d", "age": 30},
+		{"name": "e", "age": 20},
+	})
+	assert.Nil(t, err)
+
+	var (
+		cursor string
+		names  []string
+	)
+	for i := 0; i < 5; i++ {
+		var records []User
+		cursor, err = builder.Wrap(Table("user")).Keyset(ctx, []string{"-age", "id"}, cursor, 2, &records)
+		assert.Nil(t, err)
+		for _, v := range records {
+			names = append(names, v.Name)
+		}
+		if len(cursor) == 0 {
+			break
+		}
+	}
+	assert.Equal(t, []string{"b", "d", "a", "c", "e"}, names)
+}
+
+func TestSQLNestedTransaction(t *testing.T) {
+	ctx := context.Background()
+	builder := sqliteBuilder(t)
+
+	errInner := errors.New("inner failed")
+
+	err := builder.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		if _, err := tx.Wrap(Table("user")).Insert(ctx, X{"name": "outer"}); err != nil {
+			return err
+		}
+
+		// 内层失败，仅回滚内层
+		err := tx.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+			if _, err := tx.Wrap(Table("user")).Insert(ctx, X{"name": "inner_1"}); err != nil {
+				return err
+			}
+			return errInner
+		})
+		assert.ErrorIs(t, err, errInner)
+
+		// 内层成功
+		return tx.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+			_, err := tx.Wrap(Table("user")).Insert(ctx, X{"name": "inner_2"})
+			if err != nil {
+				return err
+			}
+			// 多层嵌套
+			return tx.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+				_, err := tx.Wrap(Table("user")).Insert(ctx, X{"name": "inner_3"})
+				return err
+			})
+		})
+	})
+	assert.Nil(t, err)
+
+	var names []string
+	err = builder.Wrap(Table("user"), OrderBy("id")).Pluck(ctx, "name", &names)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"outer", "inner_2", "inner_3"}, names)
+
+	// 外层失败，全部回滚
+	err = builder.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		_ = tx.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+			_, err := tx.Wrap(Table("user")).Insert(ctx, X{"name": "inner_4"})
+			return err
+		})
+		return errInner
+	})
+	assert.ErrorIs(t, err, errInner)
+
+	total, err := builder.Wrap(Table("user")).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestSQLTransactionCallback(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		traces []string
+		panics []any
+	)
+
+	builder := sqliteBuilder(t, WithTxPanicFn(func(ctx context.Context, err any, stack []byte) {
+		panics = append(panics, err)
+	}))
+
+	errOops := errors.New("oops")
+
+	err := builder.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		tx.OnCommit(func(ctx context.Context) { traces = append(traces, "commit_1") })
+		tx.OnCommit(func(ctx context.Context) { panic("boom") })
+		tx.OnCommit(func(ctx context.Context) { traces = append(traces, "commit_2") })
+		tx.OnRollback(func(ctx context.Context, err error) { traces = append(traces, "rollback_1") })
+
+		// 内层回滚：立即执行内层回滚回调，丢弃内层提交回调
+		_ = tx.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+			tx.OnCommit(func(ctx context.Context) { traces = append(traces, "inner_commit_1") })
+			tx.OnRollback(func(ctx context.Context, err error) {
+				traces = append(traces, "inner_rollback_1:"+err.Error())
+			})
+			return errOops
+		})
+
+		// 内层提交：回调随外层事务执行
+		return tx.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+			tx.OnCommit(func(ctx context.Context) { traces = append(traces, "inner_commit_2") })
+			return nil
+		})
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"inner_rollback_1:oops", "commit_1", "commit_2", "inner_commit_2"}, traces)
+	assert.Equal(t, []any{"boom"}, panics)
+
+	traces = traces[:0]
+
+	err = builder.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		tx.OnCommit(func(ctx context.Context) { traces = append(traces, "commit") })
+		tx.OnRollback(func(ctx context.Context, err error) { traces = append(traces, "rollback:"+err.Error()) })
+		return errOops
+	})
+	assert.ErrorIs(t, err, errOops)
+	assert.Equal(t, []string{"rollback:oops"}, traces)
+
+	traces = traces[:0]
+
+	// 非事务中立即执行
+	builder.OnCommit(func(ctx context.Context) { traces = append(traces, "commit") })
+	builder.OnRollback(func(ctx context.Context, err error) { traces = append(traces, "rollback") })
+	assert.Equal(t, []string{"commit"}, traces)
+}
+
+func TestSQLWrapperExplain(t *testing.T) {
+	builder := sqliteBuilder(t)
+
+	plans, err := builder.Wrap(
+		Table("user"),
+		Where("id = ?", 1),
+	).Explain(context.Background())
+	assert.Nil(t, err)
+	assert.NotEmpty(t, plans)
+	assert.Contains(t, plans[0], "detail")
+}
+
+func TestSQLIter(t *testing.T) {
+	type User struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	builder := sqliteBuilder(t)
+	_, err := builder.Wrap(Table("user")).BatchInsert(ctx, []X{
+		{"name": "a", "age": 10},
+		{"name": "b", "age": 20},
+		{"name": "c", "age": 30},
+	})
+	assert.Nil(t, err)
+
+	names := make([]string, 0)
+	err = SQLEach(ctx, builder.Wrap(Table("user"), OrderBy("id")), func(row User) error {
+		names = append(names, row.Name)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	// 中途退出需关闭结果集 (单连接，未关闭时后续查询会阻塞)
+	for row, err := range SQLIter[*User](ctx, builder.Wrap(Table("user"), OrderBy("id"))) {
+		assert.Nil(t, err)
+		assert.Equal(t, "a", row.Name)
+		break
+	}
+
+	stop := errors.New("stop")
+	err = SQLEach(ctx, builder.Wrap(Table("user")), func(row X) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+
+	ages := make([]int64, 0)
+	for age, err := range SQLIter[int64](ctx, builder.Wrap(Table("user"), Select("age"), OrderBy("id"))) {
+		assert.Nil(t, err)
+		ages = append(ages, age)
+	}
+	assert.Equal(t, []int64{10, 20, 30}, ages)
+
+	_, err = builder.Wrap(Table("user"), OrderByParam("unknown", nil)).Rows(ctx)
+	assert.NotNil(t, err)
+}
+
+type maxParamsDialect struct {
+	Dialect
+	max int
+}
+
+func (d *maxParamsDialect) MaxParams() int {
+	return d.max
+}
+
+func TestSQLWrapperChunkInsert(t *testing.T) {
+	ctx := context.Background()
+
+	var queries []string
+
+	builder := sqliteBuilder(t,
+		WithDialect(&maxParamsDialect{Dialect: SQLiteDialect(), max: 4}),
+		WithSQLLogger(func(ctx context.Context, query string, args ...any) {
+			queries = append(queries, query)
+		}),
+	)
+
+	data := []X{
+		{"name": "a", "age": 10},
+		{"name": "b", "age": 20},
+		{"name": "c", "age": 30},
+		{"name": "d", "age": 40},
+		{"name": "e", "age": 50},
+	}
+
+	// 每批最多 min(3, 4/2) = 2 行
+	n, err := builder.Wrap(Table("user")).ChunkInsert(ctx, data, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Len(t, queries, 3)
+	assert.True(t, strings.HasSuffix(queries[0], "VALUES (?, ?), (?, ?)"))
+	assert.True(t, strings.HasSuffix(queries[1], "VALUES (?, ?), (?, ?)"))
+	assert.True(t, strings.HasSuffix(queries[2], "VALUES (?, ?)"))
+
+	total, err := builder.Wrap(Table("user")).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), total)
+
+	// 任一批失败则全部回滚
+	_, err = builder.Wrap(Table("user")).ChunkInsert(ctx, []X{
+		{"name": "f", "age": 60},
+		{"name": "g", "age": 70},
+		{"name": nil, "age": 80},
+	}, 2)
+	assert.NotNil(t, err)
+
+	total, err = builder.Wrap(Table("user")).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), total)
+
+	// 在事务中使用
+	err = builder.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		n, err := tx.Wrap(Table("user")).ChunkInsert(ctx, data[:3], 0)
+		assert.Equal(t, int64(3), n)
+		return err
+	})
+	assert.Nil(t, err)
+
+	total, err = builder.Wrap(Table("user")).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(8), total)
+}
+
+func TestSQLCopyFrom(t *testing.T) {
+	ctx := context.Background()
+
+	var queries []string
+
+	builder := sqliteBuilder(t, WithSQLLogger(func(ctx context.Context, query string, args ...any) {
+		queries = append(queries, query)
+	}))
+
+	_, err := builder.CopyFrom(ctx, "user", []X{{"name": "yiigo"}})
+	assert.EqualError(t, err, "copy from is not supported by dialect: sqlite")
+	assert.Len(t, queries, 0)
+
+	type User struct {
+		ID   int    `db:"id,pk"`
+		Name string `db:"name"`
+		Age  int    `db:"age,omitempty"`
+	}
+
+	w := warpperWithDriver("postgres", Table("user"))
+
+	columns, args, err := w.batchInsertValues([]*User{{Name: "a", Age: 10}, {Name: "b"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name", "age"}, columns)
+
+	var rows [][]any
+	src := copyFromRows(args, len(columns))
+	for src.Next() {
+		values, err := src.Values()
+		assert.Nil(t, err)
+		rows = append(rows, values)
+	}
+	assert.Equal(t, [][]any{{"a", 10}, {"b", 0}}, rows)
+
+	data := []X{{"name": "a", "age": 10}, {"name": "b", "age": 20}}
+	columns, args, err = w.batchInsertValues(data)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"name", "age"}, columns)
+
+	i := 0
+	src = copyFromRows(args, len(columns))
+	for ; src.Next(); i++ {
+		values, err := src.Values()
+		assert.Nil(t, err)
+		for j, column := range columns {
+			assert.Equal(t, data[i][column], values[j])
+		}
+	}
+	assert.Equal(t, 2, i)
+
+	_, _, err = w.batchInsertValues(X{"name": "yiigo"})
+	assert.ErrorIs(t, err, ErrSQLBatchDataType)
+}
+
+func TestSQLScope(t *testing.T) {
+	ctx := context.Background()
+
+	db := sqliteDB(t)
+	_, err := db.Exec("ALTER TABLE user ADD COLUMN deleted_at DATETIME")
+	assert.Nil(t, err)
+
+	builder := NewSQLBuilderWithOptions(db,
+		WithSoftDelete("deleted_at", "user"),
+		WithSQLScope("user", "adult", Where("age >= ?", 18)),
+	)
+
+	sql, args, err := builder.Wrap(
+		Table("user"),
+		Where("name = ?", "a"),
+		WhereOr("name = ?", "b"),
+	).ToQuery()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "user" WHERE ((name = ?) OR (name = ?)) AND ("user"."deleted_at" IS NULL) AND (age >= ?)`, sql)
+	assert.Equal(t, []any{"a", "b", 18}, args)
+
+	sql, _, err = builder.Wrap(
+		Table("user u"),
+		Unscoped("adult"),
+		Where("u.id = ?", 1),
+	).ToDelete()
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "user" "u" SET "deleted_at" = CURRENT_TIMESTAMP WHERE (u.id = ?) AND ("u"."deleted_at" IS NULL)`, sql)
+
+	_, err = builder.Wrap(Table("user")).BatchInsert(ctx, []X{
+		{"name": "a", "age": 20},
+		{"name": "b", "age": 30},
+		{"name": "c", "age": 10},
+	})
+	assert.Nil(t, err)
+
+	_, err = builder.Wrap(Table("user"), Where("name = ?", "a")).Delete(ctx)
+	assert.Nil(t, err)
+
+	total, err := builder.Wrap(Table("user")).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
+
+	total, err = builder.Wrap(Table("user"), Unscoped(SoftDeleteScope)).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total)
+
+	// 物理删除
+	_, err = builder.Wrap(Table("user"), Unscoped(), Where("name = ?", "a")).Delete(ctx)
+	assert.Nil(t, err)
+
+	total, err = builder.Wrap(Table("user"), Unscoped()).Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total)
+}
+
+func TestSQLAutoTimeAndVersion(t *testing.T) {
+	type User struct {
+		ID        int64     `db:"id"`
+		Name      string    `db:"name"`
+		Age       int       `db:"age"`
+		Version   int       `db:"version,version"`
+		CreatedAt time.Time `db:"created_at,autoCreateTime"`
+		UpdatedAt int64     `db:"updated_at,autoUpdateTime"`
+	}
+
+	user := &User{Name: "yiigo", Age: 29}
+
+	sql, args, err := warpper(Table("user")).insertSQL(user)
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO user (id, name, age, version, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)", sql)
+	assert.Equal(t, []any{int64(0), "yiigo", 29, 0}, args[:4])
+	assert.False(t, args[4].(time.Time).IsZero())
+	assert.NotZero(t, args[5])
+	// 生成SQL时不回填
+	assert.True(t, user.CreatedAt.IsZero())
+	assert.Zero(t, user.UpdatedAt)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("id = ?", 1),
+	).updateSQL(&User{Name: "yiigo", Age: 30, Version: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE user SET id = ?, name = ?, age = ?, version = version + 1, updated_at = ? WHERE (id = ?) AND (version = ?)", sql)
+	assert.Len(t, args, 6)
+	assert.Equal(t, []any{int64(0), "yiigo", 30}, args[:3])
+	assert.Equal(t, []any{1, 2}, args[4:])
+
+	// 乐观锁条件不受 `OR` 影响
+	sql, args, err = warpper(
+		Table("user"),
+		Where("id = ?", 1),
+		WhereOr("name = ?", "yiigo"),
+	).updateSQL(X{"version": SQLExpr("version + 1")})
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE user SET version = version + 1 WHERE (id = ?) OR (name = ?)", sql)
+	assert.Equal(t, []any{1, "yiigo"}, args)
+
+	sql, args, err = warpper(
+		Table("user"),
+		Where("id = ?", 1),
+		WhereOr("name = ?", "yiigo"),
+	).updateSQL(&User{Name: "yiigo", Age: 30, Version: 2})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(sql, " WHERE ((id = ?) OR (name = ?)) AND (version = ?)"))
+	assert.Equal(t, []any{1, "yiigo", 2}, args[4:])
+
+	ctx := context.Background()
+
+	db := sqliteDB(t)
+	_, err = db.Exec("ALTER TABLE user ADD COLUMN version INTEGER NOT NULL DEFAULT 0")
+	assert.Nil(t, err)
+	_, err = db.Exec("ALTER TABLE user ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0")
+	assert.Nil(t, err)
+
+	type Record struct {
+		Name      string `db:"name"`
+		Version   int    `db:"version,version"`
+		UpdatedAt int64  `db:"updated_at,autoUpdateTime"`
+	}
+
+	builder := NewSQLBuilder(db, nil)
+	inserted := &Record{Name: "yiigo"}
+	_, err = builder.Wrap(Table("user")).Insert(ctx, inserted)
+	assert.Nil(t, err)
+	assert.NotZero(t, inserted.UpdatedAt)
+
+	record := &Record{Name: "a"}
+	_, err = builder.Wrap(Table("user"), Where("id = ?", 1)).Update(ctx, record)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, record.Version)
+	assert.NotZero(t, record.UpdatedAt)
+
+	// 版本已过期，不回填
+	stale := &Record{Name: "b"}
+	_, err = builder.Wrap(Table("user"), Where("id = ?", 1)).Update(ctx, stale)
+	assert.ErrorIs(t, err, ErrStaleVersion)
+	assert.Zero(t, stale.UpdatedAt)
+
+	// 生成SQL时不回填
+	_, _, err = builder.Wrap(Table("user"), Where("id = ?", 1)).ToUpdate(stale)
+	assert.Nil(t, err)
+	assert.Zero(t, stale.UpdatedAt)
+
+	_, err = builder.Wrap(Table("user"), Where("id = ?", 1)).Update(ctx, record)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, record.Version)
+}
+
+func TestSQLUpdateFields(t *testing.T) {
+	type User struct {
+		ID    int64  `db:"id,pk"`
+		Name  string `db:"name"`
+		Age   int    `db:"age,omitempty"`
+		Vip   bool   `db:"vip,omitempty"`
+		Phone string `db:"phone"`
+	}
+
+	old := User{ID: 1, Name: "yiigo", Age: 29, Vip: true, Phone: "10086"}
+	user := old
+	user.Age = 0
+	user.Vip = false
+
+	fields, err := SQLDiff(&old, &user)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"age", "vip"}, fields)
+
+	sql, args, err := warpper(
+		Table("user"),
+		Where("id = ?", 1),
+	).updateSQL(&user, fields...)
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE user SET age = ?, vip = ? WHERE (id = ?)", sql)
+	assert.Equal(t, []any{0, false, 1}, args)
+
+	_, _, err = warpper(Table("user")).updateSQL(&user, "id")
+	assert.EqualError(t, err, "invalid update field: id")
+
+	_, err = SQLDiff(old, X{})
+	assert.ErrorIs(t, err, ErrSQLDataType)
+
+	ctx := context.Background()
+
+	builder := sqliteBuilder(t)
+	_, err = builder.Wrap(Table("user")).Insert(ctx, X{"name": "yiigo", "age": 29})
+	assert.Nil(t, err)
+
+	_, err = builder.Wrap(Table("user"), Where("id = ?", 1)).UpdateFields(ctx, &User{Name: "ignored"}, "age")
+	assert.Nil(t, err)
+
+	var record struct {
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	err = builder.Wrap(Table("user"), Select("name", "age"), Where("id = ?", 1)).One(ctx, &record)
+	assert.Nil(t, err)
+	assert.Equal(t, "yiigo", record.Name)
+	assert.Equal(t, 0, record.Age)
+
+	_, err = builder.Wrap(Table("user")).UpdateFields(ctx, &User{})
+	assert.NotNil(t, err)
+}
+
+func TestToBatchInsertPK(t *testing.T) {
+	type User struct {
+		ID   int64  `db:"id,pk"`
+		Name string `db:"name"`
+		Age  int    `db:"age,omitempty"`
+	}
+
+	// 字段由首行决定
+	sql, args, err := warpper(Table("user")).batchInsertSQL([]User{{Name: "a"}, {Name: "b", Age: 20}})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO user (name) VALUES (?), (?)", sql)
+	assert.Equal(t, []any{"a", "b"}, args)
+
+	sql, args, err = warpper(Table("user")).batchInsertSQL([]User{{ID: 1, Name: "a", Age: 10}, {ID: 2, Name: "b"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT INTO user (id, name, age) VALUES (?, ?, ?), (?, ?, ?)", sql)
+	assert.Equal(t, []any{int64(1), "a", 10, int64(2), "b", 0}, args)
+
+	// 主键零值与非零值混合
+	_, _, err = warpper(Table("user")).batchInsertSQL([]User{{Name: "a"}, {ID: 5, Name: "b"}})
+	assert.NotNil(t, err)
+
+	_, _, err = warpper(Table("user")).batchInsertSQL([]*User{{ID: 5, Name: "a"}, {Name: "b"}})
+	assert.NotNil(t, err)
+}
+
+func TestSQLUntaggedField(t *testing.T) {
+	type User struct {
+		Name string
+		Age  int `db:"age"`
+	}
+
+	sql, args, err := warpperWithDriver("postgres", Table("user")).insertSQL(&User{Name: "yiigo", Age: 29})
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("name", "age") VALUES (?, ?)`, sql)
+	assert.Equal(t, []any{"yiigo", 29}, args)
+
+	sql, _, err = warpperWithDriver("postgres", Table("user")).batchInsertSQL([]User{{Name: "a"}, {Name: "b"}})
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("name", "age") VALUES (?, ?), (?, ?)`, sql)
+
+	sql, _, err = warpperWithDriver("postgres", Table("user"), Where("id = ?", 1)).updateSQL(&User{Name: "yiigo", Age: 29})
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "user" SET "name" = ?, "age" = ? WHERE (id = ?)`, sql)
+
+	fields, err := SQLDiff(&User{Name: "a"}, &User{Name: "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"name"}, fields)
 }
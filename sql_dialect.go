@@ -0,0 +1,350 @@
+package yiigo
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect SQL方言；
+// Upsert、JSON、行锁、游标分页的行值比较、`UPDATE`/`DELETE` 关联表等语法根据 `Name` 选择，
+// 仅支持内置的 mysql, postgres 和 sqlite，其他名称使用这些特性时返回不支持的错误 (游标分页使用通用写法)；
+// 自定义方言如与内置方言语法兼容，可返回其名称 (如：CockroachDB 返回 postgres)
+type Dialect interface {
+	// Name 方言名称 (mysql, postgres, sqlite)，其他驱动为驱动名称
+	Name() string
+	// BindType 占位符类型 (sqlx.QUESTION, sqlx.DOLLAR 等)
+	BindType() int
+	// Quote 为单个标识符添加引号，例如：`user` 或 "user"
+	Quote(ident string) string
+	// Limit 生成 `LIMIT` 和 `OFFSET` 子句 (值为0表示未指定)
+	Limit(limit, offset int) (string, []any)
+	// Returning 是否支持 `RETURNING` 子句
+	Returning() bool
+	// Truncate 生成清空表语句 (表名已添加引号)
+	Truncate(table string) string
+	// Explain 生成查询计划语句
+	Explain(query string) string
+	// MaxParams 单条语句的最大参数数量 (0 表示不限制)
+	MaxParams() int
+}
+
+const (
+	dialectMySQL    = "mysql"
+	dialectPostgres = "postgres"
+	dialectSQLite   = "sqlite"
+)
+
+// NewDialect 根据驱动名称返回对应的方言；
+// 未知驱动不添加引号，占位符类型由 `sqlx.BindType` 决定
+func NewDialect(driver string) Dialect {
+	switch {
+	case isMySQL(driver):
+		return MySQLDialect()
+	case isPostgres(driver):
+		return PostgresDialect()
+	case isSQLite(driver):
+		return SQLiteDialect()
+	}
+	return &genericDialect{
+		name:     driver,
+		bindType: sqlx.BindType(driver),
+	}
+}
+
+// MySQLDialect MySQL方言
+func MySQLDialect() Dialect {
+	return new(mysqlDialect)
+}
+
+// PostgresDialect Postgres方言
+func PostgresDialect() Dialect {
+	return new(postgresDialect)
+}
+
+// SQLiteDialect SQLite方言
+func SQLiteDialect() Dialect {
+	return new(sqliteDialect)
+}
+
+type mysqlDialect struct{}
+
+func (d *mysqlDialect) Name() string {
+	return dialectMySQL
+}
+
+func (d *mysqlDialect) BindType() int {
+	return sqlx.QUESTION
+}
+
+func (d *mysqlDialect) Quote(ident string) string {
+	return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+}
+
+func (d *mysqlDialect) Limit(limit, offset int) (string, []any) {
+	// MySQL 不支持单独使用 `OFFSET`
+	if limit <= 0 && offset > 0 {
+		return "LIMIT 18446744073709551615 OFFSET ?", []any{offset}
+	}
+	return limitOffset(limit, offset)
+}
+
+func (d *mysqlDialect) Returning() bool {
+	return false
+}
+
+func (d *mysqlDialect) Truncate(table string) string {
+	return "TRUNCATE TABLE " + table
+}
+
+func (d *mysqlDialect) Explain(query string) string {
+	return "EXPLAIN " + query
+}
+
+func (d *mysqlDialect) MaxParams() int {
+	return 65535
+}
+
+type postgresDialect struct{}
+
+func (d *postgresDialect) Name() string {
+	return dialectPostgres
+}
+
+func (d *postgresDialect) BindType() int {
+	return sqlx.DOLLAR
+}
+
+func (d *postgresDialect) Quote(ident string) string {
+	return quoteDouble(ident)
+}
+
+func (d *postgresDialect) Limit(limit, offset int) (string, []any) {
+	return limitOffset(limit, offset)
+}
+
+func (d *postgresDialect) Returning() bool {
+	return true
+}
+
+func (d *postgresDialect) Truncate(table string) string {
+	return "TRUNCATE TABLE " + table
+}
+
+func (d *postgresDialect) Explain(query string) string {
+	return "EXPLAIN " + query
+}
+
+func (d *postgresDialect) MaxParams() int {
+	return 65535
+}
+
+type sqliteDialect struct{}
+
+func (d *sqliteDialect) Name() string {
+	return dialectSQLite
+}
+
+func (d *sqliteDialect) BindType() int {
+	return sqlx.QUESTION
+}
+
+func (d *sqliteDialect) Quote(ident string) string {
+	return quoteDouble(ident)
+}
+
+func (d *sqliteDialect) Limit(limit, offset int) (string, []any) {
+	// SQLite 不支持单独使用 `OFFSET`
+	if limit <= 0 && offset > 0 {
+		return "LIMIT -1 OFFSET ?", []any{offset}
+	}
+	return limitOffset(limit, offset)
+}
+
+func (d *sqliteDialect) Returning() bool {
+	return true
+}
+
+func (d *sqliteDialect) Truncate(table string) string {
+	// SQLite 不支持 `TRUNCATE`
+	return "DELETE FROM " + table
+}
+
+func (d *sqliteDialect) Explain(query string) string {
+	return "EXPLAIN QUERY PLAN " + query
+}
+
+func (d *sqliteDialect) MaxParams() int {
+	// SQLITE_MAX_VARIABLE_NUMBER (3.32.0)
+	return 32766
+}
+
+type genericDialect struct {
+	name     string
+	bindType int
+}
+
+func (d *genericDialect) Name() string {
+	return d.name
+}
+
+func (d *genericDialect) BindType() int {
+	return d.bindType
+}
+
+func (d *genericDialect) Quote(ident string) string {
+	return ident
+}
+
+func (d *genericDialect) Limit(limit, offset int) (string, []any) {
+	return limitOffset(limit, offset)
+}
+
+func (d *genericDialect) Returning() bool {
+	return true
+}
+
+func (d *genericDialect) Truncate(table string) string {
+	return "TRUNCATE " + table
+}
+
+func (d *genericDialect) Explain(query string) string {
+	return "EXPLAIN " + query
+}
+
+func (d *genericDialect) MaxParams() int {
+	return 0
+}
+
+func limitOffset(limit, offset int) (string, []any) {
+	var (
+		builder strings.Builder
+		args    []any
+	)
+
+	if limit > 0 {
+		builder.WriteString("LIMIT ?")
+		args = append(args, limit)
+	}
+	if offset > 0 {
+		if builder.Len() != 0 {
+			builder.WriteString(" ")
+		}
+		builder.WriteString("OFFSET ?")
+		args = append(args, offset)
+	}
+	return builder.String(), args
+}
+
+func quoteDouble(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+var identRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// quoteIdent 为标识符添加引号，支持 `table.column` 和 `table.*`；非标识符 (如：表达式) 原样返回
+func quoteIdent(d Dialect, s string) (string, bool) {
+	parts := strings.Split(s, ".")
+	for i, v := range parts {
+		if v == "*" && i != 0 && i == len(parts)-1 {
+			continue
+		}
+		if !identRegex.MatchString(v) {
+			return s, false
+		}
+	}
+	for i, v := range parts {
+		if v != "*" {
+			parts[i] = d.Quote(v)
+		}
+	}
+	return strings.Join(parts, "."), true
+}
+
+// sqlKeywords 关键字和特殊值，字段表达式以其开头或结尾时不视为 `name alias`，单独出现时不添加引号
+var sqlKeywords = map[string]struct{}{
+	"ALL": {}, "AND": {}, "ANY": {}, "AS": {}, "ASC": {}, "BETWEEN": {}, "BY": {}, "CASE": {},
+	"CAST": {}, "DESC": {}, "DISTINCT": {}, "ELSE": {}, "END": {}, "EXISTS": {}, "FALSE": {}, "FROM": {},
+	"IN": {}, "INTERVAL": {}, "IS": {}, "LATERAL": {}, "LIKE": {}, "NOT": {}, "NULL": {}, "ONLY": {},
+	"OR": {}, "SELECT": {}, "SOME": {}, "THEN": {}, "TRUE": {}, "UNIQUE": {}, "WHEN": {}, "WHERE": {},
+	"DEFAULT": {}, "UNKNOWN": {}, "CURRENT_DATE": {}, "CURRENT_TIME": {}, "CURRENT_TIMESTAMP": {},
+	"CURRENT_USER": {}, "SESSION_USER": {}, "LOCALTIME": {}, "LOCALTIMESTAMP": {},
+}
+
+func isSQLKeyword(s string) bool {
+	_, ok := sqlKeywords[strings.ToUpper(s)]
+	return ok
+}
+
+// quoteColumn 为字段名或表名添加引号，支持别名：`name alias` 和 `name AS alias`；
+// 关键字、特殊值 (如：NULL, CURRENT_TIMESTAMP) 和表达式 (包括以关键字开头或结尾的，如：DISTINCT name) 原样返回
+func quoteColumn(d Dialect, s string) string {
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		if isSQLKeyword(fields[0]) {
+			return s
+		}
+		v, _ := quoteIdent(d, fields[0])
+		return v
+	case 2, 3:
+		if len(fields) == 3 && !strings.EqualFold(fields[1], "AS") {
+			return s
+		}
+		alias := fields[len(fields)-1]
+		if isSQLKeyword(fields[0]) || isSQLKeyword(alias) || !identRegex.MatchString(alias) {
+			return s
+		}
+		name, ok := quoteIdent(d, fields[0])
+		if !ok {
+			return s
+		}
+		if len(fields) == 3 {
+			return name + " AS " + d.Quote(alias)
+		}
+		return name + " " + d.Quote(alias)
+	}
+	return s
+}
+
+// quoteOrder 为排序字段添加引号，支持：`column ASC|DESC [NULLS FIRST|LAST]`；表达式原样返回
+func quoteOrder(d Dialect, s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return s
+	}
+	for _, v := range fields[1:] {
+		switch strings.ToUpper(v) {
+		case "ASC", "DESC", "NULLS", "FIRST", "LAST":
+		default:
+			return s
+		}
+	}
+	name, ok := quoteIdent(d, fields[0])
+	if !ok {
+		return s
+	}
+	fields[0] = name
+	return strings.Join(fields, " ")
+}
+
+func isMySQL(driver string) bool {
+	switch driver {
+	case "mysql", "nrmysql":
+		return true
+	}
+	return false
+}
+
+func isPostgres(driver string) bool {
+	return sqlx.BindType(driver) == sqlx.DOLLAR
+}
+
+func isSQLite(driver string) bool {
+	switch driver {
+	case "sqlite3", "nrsqlite3", "sqlite":
+		return true
+	}
+	return false
+}
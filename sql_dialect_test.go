@@ -0,0 +1,121 @@
+package yiigo
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDialect(t *testing.T) {
+	assert.Equal(t, dialectMySQL, NewDialect("mysql").Name())
+	assert.Equal(t, dialectPostgres, NewDialect("pgx").Name())
+	assert.Equal(t, dialectSQLite, NewDialect("sqlite3").Name())
+	assert.Equal(t, sqlx.DOLLAR, NewDialect("postgres").BindType())
+	assert.Equal(t, sqlx.QUESTION, NewDialect("mysql").BindType())
+	assert.Equal(t, "order", NewDialect("").Quote("order"))
+}
+
+func TestSQLQuote(t *testing.T) {
+	d := MySQLDialect()
+
+	assert.Equal(t, "`order`", quoteColumn(d, "order"))
+	assert.Equal(t, "`order`.`id`", quoteColumn(d, "order.id"))
+	assert.Equal(t, "`order`.*", quoteColumn(d, "order.*"))
+	assert.Equal(t, "*", quoteColumn(d, "*"))
+	assert.Equal(t, "`user` `u`", quoteColumn(d, "user u"))
+	assert.Equal(t, "`u`.`name` AS `username`", quoteColumn(d, "u.name as username"))
+	assert.Equal(t, "COUNT(*) AS total", quoteColumn(d, "COUNT(*) AS total"))
+	assert.Equal(t, "DISTINCT name", quoteColumn(d, "DISTINCT name"))
+	assert.Equal(t, "distinct name AS n", quoteColumn(d, "distinct name AS n"))
+	assert.Equal(t, "name DESC", quoteColumn(d, "name DESC"))
+	assert.Equal(t, "NOT vip", quoteColumn(d, "NOT vip"))
+	assert.Equal(t, "`a``b`", d.Quote("a`b"))
+
+	assert.Equal(t, "`created_at` DESC NULLS LAST", quoteOrder(d, "created_at DESC NULLS LAST"))
+	assert.Equal(t, "FIELD(id, 3, 1, 2)", quoteOrder(d, "FIELD(id, 3, 1, 2)"))
+	assert.Equal(t, "id; DROP TABLE user", quoteOrder(d, "id; DROP TABLE user"))
+
+	assert.Equal(t, `"user"."name"`, quoteColumn(PostgresDialect(), "user.name"))
+}
+
+func TestSQLDialect(t *testing.T) {
+	sql, args, err := warpperWithDriver("mysql",
+		Table("order"),
+		Select("id", "user"),
+		Where("amount > ?", 100),
+		OrderBy("id DESC"),
+		Offset(10),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT `id`, `user` FROM `order` WHERE (amount > ?) ORDER BY `id` DESC LIMIT 18446744073709551615 OFFSET ?", sql)
+	assert.Equal(t, []any{100, 10}, args)
+
+	sql, args, err = warpperWithDriver("sqlite3",
+		Table("order"),
+		Offset(10),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "order" LIMIT -1 OFFSET ?`, sql)
+	assert.Equal(t, []any{10}, args)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("order"),
+		Offset(10),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT * FROM "order" OFFSET ?`, sql)
+	assert.Equal(t, []any{10}, args)
+
+	sql, _, err = warpperWithDriver("mysql",
+		Table("user"),
+		Select("DISTINCT name"),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT DISTINCT name FROM `user`", sql)
+
+	sql, _, err = warpperWithDriver("postgres",
+		Table("user"),
+		Select("id", "CURRENT_TIMESTAMP", "true", "NULL", "localtime"),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "id", CURRENT_TIMESTAMP, true, NULL, localtime FROM "user"`, sql)
+
+	_, _, err = warpperWithDriver("mysql",
+		Table("user"),
+		Returning("id"),
+	).insertSQL(X{"name": "yiigo"})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "TRUNCATE TABLE `user`", warpperWithDriver("mysql", Table("user")).truncateSQL())
+	assert.Equal(t, `TRUNCATE TABLE "user"`, warpperWithDriver("postgres", Table("user")).truncateSQL())
+	assert.Equal(t, `DELETE FROM "user"`, warpperWithDriver("sqlite3", Table("user")).truncateSQL())
+}
+
+type cockroachDialect struct {
+	Dialect
+}
+
+func (d *cockroachDialect) Name() string {
+	return dialectPostgres
+}
+
+func TestSQLCustomDialect(t *testing.T) {
+	d := &cockroachDialect{Dialect: PostgresDialect()}
+
+	sql, args, err := newSQLWrapper(nil, d, nil, []SQLOption{
+		Table("user"),
+		OnConflictUpdate([]string{"id"}, []string{"name"}),
+	}).insertSQL(X{"id": 1, "name": "yiigo"})
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT INTO "user" ("id", "name") VALUES (?, ?) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`, sql)
+	assert.Equal(t, []any{1, "yiigo"}, args)
+
+	savepoint, rollback, release := savepointSQL(NewDialect("sqlserver"), "sp")
+	assert.Equal(t, "SAVE TRANSACTION sp", savepoint)
+	assert.Equal(t, "ROLLBACK TRANSACTION sp", rollback)
+	assert.Empty(t, release)
+
+	_, _, release = savepointSQL(d, "sp")
+	assert.Equal(t, "RELEASE SAVEPOINT sp", release)
+}
@@ -0,0 +1,130 @@
+package yiigo
+
+import "strings"
+
+// SQLWindow 窗口定义，用于 `Over`
+type SQLWindow struct {
+	PartitionBy []string // `PARTITION BY` 字段
+	OrderBy     []string // `ORDER BY` 字段，例如：created_at DESC
+	Frame       string   // 窗口范围，例如：ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW
+}
+
+// Over 指定窗口函数的 `OVER` 子句，例如：
+//
+//	yiigo.SQLSum("amount").Over(yiigo.SQLWindow{PartitionBy: []string{"user_id"}, OrderBy: []string{"id"}}).As("total")
+//	// SUM(amount) OVER (PARTITION BY user_id ORDER BY id) AS total
+func (c *SQLClause) Over(window SQLWindow) *SQLClause {
+	return &SQLClause{
+		build: func(d Dialect) (*SQLClause, error) {
+			v, err := c.resolve(d)
+			if err != nil {
+				return nil, err
+			}
+
+			var builder strings.Builder
+
+			builder.WriteString(v.query)
+			builder.WriteString(" OVER (")
+			window.write(&builder, d)
+			builder.WriteString(")")
+
+			return &SQLClause{
+				query: builder.String(),
+				binds: v.binds,
+			}, nil
+		},
+	}
+}
+
+func (w SQLWindow) write(builder *strings.Builder, d Dialect) {
+	var sep bool
+
+	if len(w.PartitionBy) != 0 {
+		builder.WriteString("PARTITION BY ")
+		builder.WriteString(quoteColumn(d, w.PartitionBy[0]))
+		for _, column := range w.PartitionBy[1:] {
+			builder.WriteString(", ")
+			builder.WriteString(quoteColumn(d, column))
+		}
+		sep = true
+	}
+	if len(w.OrderBy) != 0 {
+		if sep {
+			builder.WriteString(" ")
+		}
+		builder.WriteString("ORDER BY ")
+		builder.WriteString(quoteOrder(d, w.OrderBy[0]))
+		for _, column := range w.OrderBy[1:] {
+			builder.WriteString(", ")
+			builder.WriteString(quoteOrder(d, column))
+		}
+		sep = true
+	}
+	if len(w.Frame) != 0 {
+		if sep {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(w.Frame)
+	}
+}
+
+// SQLRowNumber 窗口函数 `ROW_NUMBER()`
+func SQLRowNumber() *SQLClause {
+	return SQLExpr("ROW_NUMBER()")
+}
+
+// SQLRank 窗口函数 `RANK()`
+func SQLRank() *SQLClause {
+	return SQLExpr("RANK()")
+}
+
+// SQLDenseRank 窗口函数 `DENSE_RANK()`
+func SQLDenseRank() *SQLClause {
+	return SQLExpr("DENSE_RANK()")
+}
+
+// SQLCount 聚合函数 `COUNT`，字段为空时为 `COUNT(*)`
+func SQLCount(column string) *SQLClause {
+	if len(column) == 0 || column == "*" {
+		return SQLExpr("COUNT(*)")
+	}
+	return aggregate("COUNT", column)
+}
+
+// SQLCountDistinct 聚合函数 `COUNT(DISTINCT column)`
+func SQLCountDistinct(column string) *SQLClause {
+	return aggregate("COUNT", column, "DISTINCT ")
+}
+
+// SQLSum 聚合函数 `SUM`
+func SQLSum(column string) *SQLClause {
+	return aggregate("SUM", column)
+}
+
+// SQLAvg 聚合函数 `AVG`
+func SQLAvg(column string) *SQLClause {
+	return aggregate("AVG", column)
+}
+
+// SQLMin 聚合函数 `MIN`
+func SQLMin(column string) *SQLClause {
+	return aggregate("MIN", column)
+}
+
+// SQLMax 聚合函数 `MAX`
+func SQLMax(column string) *SQLClause {
+	return aggregate("MAX", column)
+}
+
+// aggregate 生成聚合函数，字段名 (如：user.id) 会根据方言添加引号，表达式原样输出
+func aggregate(fn, column string, prefix ...string) *SQLClause {
+	return &SQLClause{
+		build: func(d Dialect) (*SQLClause, error) {
+			arg, _ := quoteIdent(d, column)
+			if len(prefix) != 0 {
+				arg = prefix[0] + arg
+			}
+			return SQLExpr(fn + "(" + arg + ")"), nil
+		},
+	}
+}
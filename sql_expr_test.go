@@ -0,0 +1,94 @@
+package yiigo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLWindow(t *testing.T) {
+	sql, args, err := warpper(
+		Table("order"),
+		SelectExpr(
+			"id",
+			SQLRowNumber().Over(SQLWindow{PartitionBy: []string{"user_id"}, OrderBy: []string{"amount DESC"}}).As("rn"),
+			SQLSum("amount").Over(SQLWindow{
+				PartitionBy: []string{"user_id"},
+				OrderBy:     []string{"id"},
+				Frame:       "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW",
+			}).As("running_total"),
+			SQLRank().Over(SQLWindow{}).As("rank"),
+			SQLExpr("amount * ?", 2).As("double"),
+		),
+		Where("status = ?", 1),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY amount DESC) AS rn, SUM(amount) OVER (PARTITION BY user_id ORDER BY id ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW) AS running_total, RANK() OVER () AS rank, amount * ? AS double FROM order WHERE (status = ?)", sql)
+	assert.Equal(t, []any{2, 1}, args)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("order"),
+		SelectExpr("user_id", SQLCount("").As("cnt"), SQLAvg("amount").As("avg"), SQLMax("o.amount"), SQLMin("amount")),
+		GroupBy("user_id"),
+		Having("? > ?", SQLSum("amount"), 100),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "user_id", COUNT(*) AS "cnt", AVG("amount") AS "avg", MAX("o"."amount"), MIN("amount") FROM "order" GROUP BY "user_id" HAVING (SUM("amount") > ?)`, sql)
+	assert.Equal(t, []any{100}, args)
+
+	sql, _, err = warpperWithDriver("mysql",
+		Table("order"),
+		SelectExpr(SQLCount("DISTINCT user_id").As("a"), SQLCountDistinct("user_id").As("b"), SQLSum("amount * price")),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT COUNT(DISTINCT user_id) AS `a`, COUNT(DISTINCT `user_id`) AS `b`, SUM(amount * price) FROM `order`", sql)
+
+	_, _, err = warpper(Table("user"), SelectExpr("id", 1)).querySQL()
+	assert.ErrorIs(t, err, ErrSQLDataType)
+
+	// Select 重置字段，SelectExpr 追加字段
+	columns := []string{"id", "name"}
+	sql, args, err = warpper(
+		Table("user"),
+		SelectExpr(SQLExpr("age + ?", 1).As("next")),
+		Select(columns...),
+		SelectExpr(SQLCount("").As("cnt")),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT id, name, COUNT(*) AS cnt FROM user", sql)
+	assert.Len(t, args, 0)
+	assert.Equal(t, []string{"id", "name"}, columns)
+}
+
+func TestSQLWindowSQLite(t *testing.T) {
+	ctx := context.Background()
+
+	builder := sqliteBuilder(t)
+
+	_, err := builder.Wrap(Table("user")).BatchInsert(ctx, []X{
+		{"name": "a", "age": 10},
+		{"name": "b", "age": 20},
+		{"name": "c", "age": 30},
+	})
+	assert.Nil(t, err)
+
+	type Rank struct {
+		Name  string `db:"name"`
+		RN    int    `db:"rn"`
+		Total int    `db:"total"`
+	}
+
+	var ranks []Rank
+	err = builder.Wrap(
+		Table("user"),
+		SelectExpr(
+			"name",
+			SQLRowNumber().Over(SQLWindow{OrderBy: []string{"age DESC"}}).As("rn"),
+			SQLSum("age").Over(SQLWindow{OrderBy: []string{"id"}}).As("total"),
+		),
+		OrderBy("id"),
+	).All(ctx, &ranks)
+	assert.Nil(t, err)
+	assert.Equal(t, []Rank{{"a", 3, 10}, {"b", 2, 30}, {"c", 1, 60}}, ranks)
+}
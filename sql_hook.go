@@ -0,0 +1,69 @@
+package yiigo
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SQLEvent SQL执行事件
+type SQLEvent struct {
+	// Query 执行的SQL语句 (已转换为驱动对应的占位符)
+	Query string
+	// Args 语句参数
+	Args []any
+	// Duration 执行耗时 (仅 `After` 中有值)
+	Duration time.Duration
+	// Result 执行结果 (仅 `After` 中有值，且仅用于 `INSERT`, `UPDATE`, `DELETE` 等语句)
+	Result sql.Result
+	// Err 执行错误 (仅 `After` 中有值)
+	Err error
+}
+
+// SQLHook SQL执行钩子；多个钩子按注册顺序执行 `Before`，按相反顺序执行 `After`
+type SQLHook interface {
+	// Before 语句执行前调用，返回的 context 将用于语句执行和 `After`，可用于注入链路追踪等
+	Before(ctx context.Context, e *SQLEvent) context.Context
+	// After 语句执行后调用
+	After(ctx context.Context, e *SQLEvent)
+}
+
+type sqlLogHook struct {
+	fn func(ctx context.Context, query string, args ...any)
+}
+
+func (h *sqlLogHook) Before(ctx context.Context, e *SQLEvent) context.Context {
+	h.fn(ctx, e.Query, e.Args...)
+	return ctx
+}
+
+func (h *sqlLogHook) After(ctx context.Context, e *SQLEvent) {}
+
+// SQLLogHook 生成一个在语句执行前打印日志的钩子
+func SQLLogHook(fn func(ctx context.Context, query string, args ...any)) SQLHook {
+	return &sqlLogHook{fn: fn}
+}
+
+// runSQLHooks 依次执行钩子和语句
+func runSQLHooks(ctx context.Context, hooks []SQLHook, query string, args []any, fn func(ctx context.Context) (sql.Result, error)) (sql.Result, error) {
+	if len(hooks) == 0 {
+		return fn(ctx)
+	}
+
+	e := &SQLEvent{
+		Query: query,
+		Args:  args,
+	}
+	for _, h := range hooks {
+		ctx = h.Before(ctx, e)
+	}
+
+	now := time.Now()
+	e.Result, e.Err = fn(ctx)
+	e.Duration = time.Since(now)
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].After(ctx, e)
+	}
+	return e.Result, e.Err
+}
@@ -0,0 +1,81 @@
+package yiigo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordHook struct {
+	name   string
+	traces *[]string
+	events []*SQLEvent
+}
+
+type hookCtxKey struct{}
+
+func (h *recordHook) Before(ctx context.Context, e *SQLEvent) context.Context {
+	*h.traces = append(*h.traces, h.name+".before")
+	return context.WithValue(ctx, hookCtxKey{}, h.name)
+}
+
+func (h *recordHook) After(ctx context.Context, e *SQLEvent) {
+	*h.traces = append(*h.traces, h.name+".after:"+ctx.Value(hookCtxKey{}).(string))
+	h.events = append(h.events, e)
+}
+
+func TestSQLHook(t *testing.T) {
+	ctx := context.Background()
+
+	var (
+		traces []string
+		logs   []string
+	)
+
+	h1 := &recordHook{name: "h1", traces: &traces}
+	h2 := &recordHook{name: "h2", traces: &traces}
+
+	builder := sqliteBuilder(t,
+		WithSQLLogger(func(ctx context.Context, query string, args ...any) {
+			logs = append(logs, query)
+		}),
+		WithSQLHook(h1, h2),
+	)
+
+	ret, err := builder.Wrap(Table("user")).Insert(ctx, X{"name": "yiigo"})
+	assert.Nil(t, err)
+	rows, _ := ret.RowsAffected()
+	assert.Equal(t, int64(1), rows)
+
+	assert.Equal(t, []string{"h1.before", "h2.before", "h2.after:h2", "h1.after:h2"}, traces)
+	assert.Equal(t, []string{`INSERT INTO "user" ("name") VALUES (?)`}, logs)
+	assert.Equal(t, `INSERT INTO "user" ("name") VALUES (?)`, h1.events[0].Query)
+	assert.Equal(t, []any{"yiigo"}, h1.events[0].Args)
+	assert.NotNil(t, h1.events[0].Result)
+	assert.Nil(t, h1.events[0].Err)
+
+	err = builder.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		var name string
+		return tx.Wrap(Table("user"), Select("name"), Where("id = ?", 100)).One(ctx, &name)
+	})
+	assert.NotNil(t, err)
+	assert.Len(t, h2.events, 2)
+	assert.Equal(t, err, h2.events[1].Err)
+	assert.Nil(t, h2.events[1].Result)
+}
+
+func TestSQLBuilderLogFn(t *testing.T) {
+	var logs []string
+
+	builder := NewSQLBuilder(sqliteDB(t), func(ctx context.Context, query string, args ...any) {
+		logs = append(logs, query)
+	})
+	_, err := builder.Wrap(Table("user")).Insert(context.Background(), X{"name": "yiigo"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`INSERT INTO "user" ("name") VALUES (?)`}, logs)
+
+	// logFn 为 nil
+	_, err = NewSQLBuilder(sqliteDB(t), nil).Wrap(Table("user")).Insert(context.Background(), X{"name": "yiigo"})
+	assert.Nil(t, err)
+}
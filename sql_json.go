@@ -0,0 +1,185 @@
+package yiigo
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONExtract 提取JSON字段指定路径的值 (文本)，路径使用 `.` 分隔，数字表示数组下标，例如：
+//
+//	yiigo.Where("? = ?", yiigo.JSONExtract("attrs", "address.city"), "shanghai")
+//	yiigo.SelectExpr("id", yiigo.JSONExtract("attrs", "tags.0").As("tag"))
+//
+//	[mysql] JSON_UNQUOTE(JSON_EXTRACT(`attrs`, '$.address.city'))
+//	[postgres] "attrs" #>> '{address,city}'
+//	[sqlite] json_extract("attrs", '$.address.city')
+func JSONExtract(column, path string) *SQLClause {
+	return jsonExpr("extract", func(d Dialect) (*SQLClause, error) {
+		column := quoteColumn(d, column)
+		switch d.Name() {
+		case dialectMySQL:
+			return SQLExpr("JSON_UNQUOTE(JSON_EXTRACT("+column+", ?))", jsonPath(path)), nil
+		case dialectPostgres:
+			return SQLExpr(column+" #>> CAST(? AS text[])", jsonPathArray(path)), nil
+		case dialectSQLite:
+			return SQLExpr("json_extract("+column+", ?)", jsonPath(path)), nil
+		}
+		return nil, nil
+	})
+}
+
+// JSONContains 判断JSON字段是否包含指定值 (值会被JSON序列化，原始JSON请使用 `json.RawMessage`)；SQLite 不支持，例如：
+//
+//	yiigo.Where("?", yiigo.JSONContains("attrs", yiigo.X{"color": "red"}))
+//
+//	[mysql] JSON_CONTAINS(`attrs`, '{"color":"red"}')
+//	[postgres] "attrs" @> '{"color":"red"}'
+func JSONContains(column string, value any) *SQLClause {
+	return jsonExpr("contains", func(d Dialect) (*SQLClause, error) {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		column := quoteColumn(d, column)
+		switch d.Name() {
+		case dialectMySQL:
+			return SQLExpr("JSON_CONTAINS("+column+", ?)", string(b)), nil
+		case dialectPostgres:
+			return SQLExpr(column+" @> CAST(? AS jsonb)", string(b)), nil
+		}
+		return nil, nil
+	})
+}
+
+// JSONHasKey 判断JSON字段是否存在指定路径，例如：
+//
+//	yiigo.Where("?", yiigo.JSONHasKey("attrs", "address.city"))
+//
+//	[mysql] JSON_CONTAINS_PATH(`attrs`, 'one', '$.address.city')
+//	[postgres] "attrs" #> '{address,city}' IS NOT NULL
+//	[sqlite] json_type("attrs", '$.address.city') IS NOT NULL
+func JSONHasKey(column, path string) *SQLClause {
+	return jsonExpr("has key", func(d Dialect) (*SQLClause, error) {
+		column := quoteColumn(d, column)
+		switch d.Name() {
+		case dialectMySQL:
+			return SQLExpr("JSON_CONTAINS_PATH("+column+", 'one', ?)", jsonPath(path)), nil
+		case dialectPostgres:
+			return SQLExpr("("+column+" #> CAST(? AS text[])) IS NOT NULL", jsonPathArray(path)), nil
+		case dialectSQLite:
+			return SQLExpr("json_type("+column+", ?) IS NOT NULL", jsonPath(path)), nil
+		}
+		return nil, nil
+	})
+}
+
+// JSONArrayContains 判断JSON字段指定路径的数组是否包含指定元素 (路径为空表示字段本身)，例如：
+//
+//	yiigo.Where("?", yiigo.JSONArrayContains("attrs", "tags", "vip"))
+//
+//	[mysql] JSON_CONTAINS(`attrs`, '"vip"', '$.tags')
+//	[postgres] ("attrs" #> '{tags}') @> '["vip"]'
+//	[sqlite] EXISTS (SELECT 1 FROM json_each("attrs", '$.tags') WHERE value = 'vip')
+func JSONArrayContains(column, path string, value any) *SQLClause {
+	return jsonExpr("array contains", func(d Dialect) (*SQLClause, error) {
+		column := quoteColumn(d, column)
+		switch d.Name() {
+		case dialectMySQL:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			return SQLExpr("JSON_CONTAINS("+column+", ?, ?)", string(b), jsonPath(path)), nil
+		case dialectPostgres:
+			b, err := json.Marshal([]any{value})
+			if err != nil {
+				return nil, err
+			}
+			return SQLExpr("("+column+" #> CAST(? AS text[])) @> CAST(? AS jsonb)", jsonPathArray(path), string(b)), nil
+		case dialectSQLite:
+			// json_each 返回元素的SQL值，仅支持基本类型
+			return SQLExpr("EXISTS (SELECT 1 FROM json_each("+column+", ?) WHERE value = ?)", jsonPath(path), value), nil
+		}
+		return nil, nil
+	})
+}
+
+// JSONSet 设置JSON字段指定路径的值 (值会被JSON序列化，原始JSON请使用 `json.RawMessage`)，用于 `Update`，例如：
+//
+//	builder.Wrap(yiigo.Table("user"), yiigo.Where("id = ?", 1)).Update(ctx, yiigo.X{"attrs": yiigo.JSONSet("attrs", "address.city", "shanghai")})
+//
+//	[mysql] JSON_SET(`attrs`, '$.address.city', CAST('"shanghai"' AS JSON))
+//	[postgres] jsonb_set("attrs", '{address,city}', '"shanghai"')
+//	[sqlite] json_set("attrs", '$.address.city', json('"shanghai"'))
+func JSONSet(column, path string, value any) *SQLClause {
+	return jsonExpr("set", func(d Dialect) (*SQLClause, error) {
+		b, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		column := quoteColumn(d, column)
+		switch d.Name() {
+		case dialectMySQL:
+			return SQLExpr("JSON_SET("+column+", ?, CAST(? AS JSON))", jsonPath(path), string(b)), nil
+		case dialectPostgres:
+			return SQLExpr("jsonb_set("+column+", CAST(? AS text[]), CAST(? AS jsonb))", jsonPathArray(path), string(b)), nil
+		case dialectSQLite:
+			return SQLExpr("json_set("+column+", ?, json(?))", jsonPath(path), string(b)), nil
+		}
+		return nil, nil
+	})
+}
+
+// jsonExpr 生成方言相关的JSON表达式，不支持的方言返回错误
+func jsonExpr(op string, fn func(d Dialect) (*SQLClause, error)) *SQLClause {
+	return &SQLClause{
+		build: func(d Dialect) (*SQLClause, error) {
+			clause, err := fn(d)
+			if err != nil {
+				return nil, err
+			}
+			if clause == nil {
+				return nil, fmt.Errorf("json %s is not supported by dialect: %s", op, d.Name())
+			}
+			return clause, nil
+		},
+	}
+}
+
+// jsonPath 生成 MySQL 和 SQLite 的JSON路径，例如：a.b.0 => $.a.b[0]
+func jsonPath(path string) string {
+	var builder strings.Builder
+
+	builder.WriteString("$")
+	for _, v := range jsonPathKeys(path) {
+		if _, err := strconv.Atoi(v); err == nil {
+			builder.WriteString("[" + v + "]")
+			continue
+		}
+		builder.WriteString(".")
+		if identRegex.MatchString(v) {
+			builder.WriteString(v)
+		} else {
+			builder.WriteString(strconv.Quote(v))
+		}
+	}
+	return builder.String()
+}
+
+// jsonPathArray 生成 Postgres 的JSON路径 (text[])，例如：a.b.0 => {"a","b","0"}
+func jsonPathArray(path string) string {
+	keys := jsonPathKeys(path)
+	for i, v := range keys {
+		keys[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+	}
+	return "{" + strings.Join(keys, ",") + "}"
+}
+
+func jsonPathKeys(path string) []string {
+	if len(path) == 0 {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
@@ -0,0 +1,116 @@
+package yiigo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONPath(t *testing.T) {
+	assert.Equal(t, "$", jsonPath(""))
+	assert.Equal(t, "$.address.city", jsonPath("address.city"))
+	assert.Equal(t, `$.tags[0]."first name"`, jsonPath("tags.0.first name"))
+	assert.Equal(t, `{"address","city"}`, jsonPathArray("address.city"))
+	assert.Equal(t, "{}", jsonPathArray(""))
+}
+
+func TestSQLJSON(t *testing.T) {
+	// mysql
+	sql, args, err := warpperWithDriver("mysql",
+		Table("user"),
+		SelectExpr("id", JSONExtract("attrs", "address.city").As("city")),
+		Where("? = ?", JSONExtract("attrs", "color"), "red"),
+		Where("?", JSONHasKey("attrs", "address")),
+		Where("?", JSONContains("attrs", X{"vip": true})),
+		Where("?", JSONArrayContains("attrs", "tags", "go")),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, "SELECT `id`, JSON_UNQUOTE(JSON_EXTRACT(`attrs`, ?)) AS `city` FROM `user` WHERE (JSON_UNQUOTE(JSON_EXTRACT(`attrs`, ?)) = ?) AND (JSON_CONTAINS_PATH(`attrs`, 'one', ?)) AND (JSON_CONTAINS(`attrs`, ?)) AND (JSON_CONTAINS(`attrs`, ?, ?))", sql)
+	assert.Equal(t, []any{"$.address.city", "$.color", "red", "$.address", `{"vip":true}`, `"go"`, "$.tags"}, args)
+
+	sql, args, err = warpperWithDriver("mysql",
+		Table("user"),
+		Where("id = ?", 1),
+	).updateSQL(X{"attrs": JSONSet("attrs", "address.city", "shanghai")})
+	assert.Nil(t, err)
+	assert.Equal(t, "UPDATE `user` SET `attrs` = JSON_SET(`attrs`, ?, CAST(? AS JSON)) WHERE (id = ?)", sql)
+	assert.Equal(t, []any{"$.address.city", `"shanghai"`, 1}, args)
+
+	// postgres
+	sql, args, err = warpperWithDriver("postgres",
+		Table("user"),
+		SelectExpr("id", JSONExtract("attrs", "address.city").As("city")),
+		Where("?", JSONHasKey("attrs", "address")),
+		Where("?", JSONContains("attrs", X{"vip": true})),
+		Where("?", JSONArrayContains("attrs", "tags", "go")),
+	).querySQL()
+	assert.Nil(t, err)
+	assert.Equal(t, `SELECT "id", "attrs" #>> CAST(? AS text[]) AS "city" FROM "user" WHERE (("attrs" #> CAST(? AS text[])) IS NOT NULL) AND ("attrs" @> CAST(? AS jsonb)) AND (("attrs" #> CAST(? AS text[])) @> CAST(? AS jsonb))`, sql)
+	assert.Equal(t, []any{`{"address","city"}`, `{"address"}`, `{"vip":true}`, `{"tags"}`, `["go"]`}, args)
+
+	sql, args, err = warpperWithDriver("postgres",
+		Table("user"),
+		Where("id = ?", 1),
+	).updateSQL(X{"attrs": JSONSet("attrs", "address.city", "shanghai")})
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "user" SET "attrs" = jsonb_set("attrs", CAST(? AS text[]), CAST(? AS jsonb)) WHERE (id = ?)`, sql)
+	assert.Equal(t, []any{`{"address","city"}`, `"shanghai"`, 1}, args)
+
+	// sqlite
+	_, _, err = warpperWithDriver("sqlite3",
+		Table("user"),
+		Where("?", JSONContains("attrs", X{"vip": true})),
+	).querySQL()
+	assert.NotNil(t, err)
+
+	_, _, err = warpperWithDriver("sqlite3",
+		Table("user"),
+	).updateSQL(X{"attrs": JSONSet("attrs", "vip", func() {})})
+	assert.NotNil(t, err)
+}
+
+func TestSQLJSONSQLite(t *testing.T) {
+	ctx := context.Background()
+
+	db := sqliteDB(t)
+	_, err := db.Exec("ALTER TABLE user ADD COLUMN attrs TEXT NOT NULL DEFAULT '{}'")
+	assert.Nil(t, err)
+
+	builder := NewSQLBuilder(db, nil)
+
+	_, err = builder.Wrap(Table("user")).BatchInsert(ctx, []X{
+		{"name": "a", "attrs": `{"color":"red","tags":["go","sql"]}`},
+		{"name": "b", "attrs": `{"color":"blue","tags":["js"],"address":{"city":"beijing"}}`},
+	})
+	assert.Nil(t, err)
+
+	var names []string
+	err = builder.Wrap(Table("user"),
+		Select("name"),
+		Where("? = ?", JSONExtract("attrs", "color"), "red"),
+	).All(ctx, &names)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, names)
+
+	names = nil
+	err = builder.Wrap(Table("user"),
+		Select("name"),
+		Where("?", JSONArrayContains("attrs", "tags", "js")),
+	).All(ctx, &names)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b"}, names)
+
+	_, err = builder.Wrap(Table("user"),
+		Where("?", JSONHasKey("attrs", "address")),
+	).Update(ctx, X{"attrs": JSONSet("attrs", "address.city", "shanghai")})
+	assert.Nil(t, err)
+
+	var city string
+	err = builder.Wrap(Table("user"),
+		SelectExpr(JSONExtract("attrs", "address.city").As("city")),
+		Where("name = ?", "b"),
+	).One(ctx, &city)
+	assert.Nil(t, err)
+	assert.Equal(t, "shanghai", city)
+}
@@ -0,0 +1,106 @@
+package yiigo
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaPolicy 从库选择策略
+type ReplicaPolicy interface {
+	// Pick 从 n 个从库中选择一个，返回其索引
+	Pick(n int) int
+	// Observe 上报从库的查询耗时
+	Observe(i int, d time.Duration)
+}
+
+type roundRobinPolicy struct {
+	next atomic.Uint64
+}
+
+func (p *roundRobinPolicy) Pick(n int) int {
+	return int((p.next.Add(1) - 1) % uint64(n))
+}
+
+func (p *roundRobinPolicy) Observe(i int, d time.Duration) {}
+
+// RoundRobinPolicy 轮询选择从库
+func RoundRobinPolicy() ReplicaPolicy {
+	return new(roundRobinPolicy)
+}
+
+type randomPolicy struct{}
+
+func (p *randomPolicy) Pick(n int) int {
+	return rand.IntN(n)
+}
+
+func (p *randomPolicy) Observe(i int, d time.Duration) {}
+
+// RandomPolicy 随机选择从库
+func RandomPolicy() ReplicaPolicy {
+	return new(randomPolicy)
+}
+
+type leastLatencyPolicy struct {
+	mutex     sync.Mutex
+	latencies []time.Duration
+}
+
+func (p *leastLatencyPolicy) Pick(n int) int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.latencies) < n {
+		p.latencies = append(p.latencies, make([]time.Duration, n-len(p.latencies))...)
+	}
+
+	index := 0
+	for i := 0; i < n; i++ {
+		// 优先选择尚未统计的从库
+		if p.latencies[i] == 0 {
+			return i
+		}
+		if p.latencies[i] < p.latencies[index] {
+			index = i
+		}
+	}
+	return index
+}
+
+func (p *leastLatencyPolicy) Observe(i int, d time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if i >= len(p.latencies) {
+		p.latencies = append(p.latencies, make([]time.Duration, i+1-len(p.latencies))...)
+	}
+	if d <= 0 {
+		d = 1
+	}
+	// 指数加权移动平均 (EWMA)
+	if p.latencies[i] == 0 {
+		p.latencies[i] = d
+	} else {
+		p.latencies[i] = (p.latencies[i]*7 + d*3) / 10
+	}
+}
+
+// LeastLatencyPolicy 选择平均查询耗时最低的从库
+func LeastLatencyPolicy() ReplicaPolicy {
+	return new(leastLatencyPolicy)
+}
+
+type primaryCtxKey struct{}
+
+// ForcePrimary 返回一个强制在主库查询的 context
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+func isForcePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryCtxKey{}).(bool)
+	return v
+}
@@ -0,0 +1,70 @@
+package yiigo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaPolicy(t *testing.T) {
+	rr := RoundRobinPolicy()
+	assert.Equal(t, []int{0, 1, 2, 0}, []int{rr.Pick(3), rr.Pick(3), rr.Pick(3), rr.Pick(3)})
+
+	r := RandomPolicy()
+	for i := 0; i < 10; i++ {
+		n := r.Pick(3)
+		assert.True(t, n >= 0 && n < 3)
+	}
+
+	ll := LeastLatencyPolicy()
+	assert.Equal(t, 0, ll.Pick(3))
+	ll.Observe(0, 30*time.Millisecond)
+	assert.Equal(t, 1, ll.Pick(3))
+	ll.Observe(1, 10*time.Millisecond)
+	assert.Equal(t, 2, ll.Pick(3))
+	ll.Observe(2, 20*time.Millisecond)
+	assert.Equal(t, 1, ll.Pick(3))
+	ll.Observe(1, 50*time.Millisecond)
+	assert.Equal(t, 2, ll.Pick(3))
+}
+
+func TestSQLReplicas(t *testing.T) {
+	ctx := context.Background()
+
+	primary := sqliteDB(t)
+	replica := sqliteDB(t)
+
+	_, err := replica.Exec("INSERT INTO user (name) VALUES ('replica')")
+	assert.Nil(t, err)
+
+	builder := NewSQLBuilderWithOptions(primary, WithSQLReplicas(replica))
+
+	_, err = builder.Wrap(Table("user")).Insert(ctx, X{"name": "primary"})
+	assert.Nil(t, err)
+
+	var name string
+
+	err = builder.Wrap(Table("user"), Select("name")).One(ctx, &name)
+	assert.Nil(t, err)
+	assert.Equal(t, "replica", name)
+
+	err = builder.Wrap(Table("user"), Select("name")).One(ForcePrimary(ctx), &name)
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", name)
+
+	err = builder.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		return tx.Wrap(Table("user"), Select("name")).One(ctx, &name)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "primary", name)
+}
+
+func TestSQLLockUsePrimary(t *testing.T) {
+	ctx := context.Background()
+
+	assert.False(t, isForcePrimary(warpper(Table("user")).readContext(ctx)))
+	assert.True(t, isForcePrimary(warpperWithDriver("mysql", Table("user"), ForUpdate()).readContext(ctx)))
+	assert.True(t, isForcePrimary(warpperWithDriver("postgres", Table("user"), ForShare(LockNoWait)).readContext(ctx)))
+}
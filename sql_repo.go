@@ -0,0 +1,160 @@
+package yiigo
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+// Repo 通用数据仓库，*T 需实现 `TableName() string`，主键由 `db:"id,pk"` 指定 (未指定时为 id)
+type Repo[T any] struct {
+	tx      TXBuilder
+	table   string
+	pk      string
+	pkIndex int // 主键字段索引 (-1 表示未找到)
+}
+
+// SQLTable 数据表模型，*T 需实现 `TableName() string`
+type SQLTable[T any] interface {
+	*T
+	TableName() string
+}
+
+// NewRepo 生成数据仓库，例如：yiigo.NewRepo[User](builder)；在事务中可使用 `WithTx` 绑定事务
+func NewRepo[T any, PT SQLTable[T]](tx TXBuilder) *Repo[T] {
+	repo := &Repo[T]{
+		tx:      tx,
+		table:   PT(new(T)).TableName(),
+		pk:      "id",
+		pkIndex: -1,
+	}
+
+	if len(repo.table) == 0 {
+		panic("yiigo: empty table name for repo")
+	}
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Struct {
+		if column, index, ok := parsePK(t); ok {
+			repo.pk = column
+			repo.pkIndex = index
+		}
+	}
+	return repo
+}
+
+// WithTx 返回绑定指定事务的数据仓库，例如：
+//
+//	builder.Transaction(ctx, func(ctx context.Context, tx yiigo.TXBuilder) error {
+//		return repo.WithTx(tx).Create(ctx, &user)
+//	})
+func (r *Repo[T]) WithTx(tx TXBuilder) *Repo[T] {
+	v := *r
+	v.tx = tx
+	return &v
+}
+
+// FindByID 根据主键查询，不存在时返回 `sql.ErrNoRows`
+func (r *Repo[T]) FindByID(ctx context.Context, id any) (*T, error) {
+	record := new(T)
+	if err := r.tx.Wrap(Table(r.table), r.wherePK(id)).One(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// FindMany 根据查询选项查询多条数据
+func (r *Repo[T]) FindMany(ctx context.Context, opts ...SQLOption) ([]T, error) {
+	var records []T
+	if err := r.wrap(opts...).All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Count 根据查询选项查询数量
+func (r *Repo[T]) Count(ctx context.Context, opts ...SQLOption) (int64, error) {
+	return r.wrap(opts...).Count(ctx)
+}
+
+// Create 插入数据；主键为零值的整型时，回填自增ID (需驱动支持 `LastInsertId`)
+func (r *Repo[T]) Create(ctx context.Context, data *T) (sql.Result, error) {
+	ret, err := r.tx.Wrap(Table(r.table), r.primaryKey()).Insert(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if pk, ok := r.pkValue(data); ok && pk.CanInt() && pk.IsZero() {
+		if id, err := ret.LastInsertId(); err == nil {
+			pk.SetInt(id)
+		}
+	}
+	return ret, nil
+}
+
+// Save 保存数据；主键为零值时插入，否则根据主键更新
+func (r *Repo[T]) Save(ctx context.Context, data *T) (sql.Result, error) {
+	pk, ok := r.pkValue(data)
+	if !ok || pk.IsZero() {
+		return r.Create(ctx, data)
+	}
+	return r.tx.Wrap(Table(r.table), r.primaryKey(), r.wherePK(pk.Interface())).Update(ctx, data)
+}
+
+// DeleteByID 根据主键删除 (软删除的表执行 `UPDATE`)
+func (r *Repo[T]) DeleteByID(ctx context.Context, id any) (sql.Result, error) {
+	return r.tx.Wrap(Table(r.table), r.wherePK(id)).Delete(ctx)
+}
+
+func (r *Repo[T]) wrap(opts ...SQLOption) SQLWrapper {
+	return r.tx.Wrap(append([]SQLOption{Table(r.table)}, opts...)...)
+}
+
+func (r *Repo[T]) wherePK(id any) SQLOption {
+	return func(w *sqlWrapper) {
+		Where(quoteColumn(w.dialect, r.pk)+" = ?", id)(w)
+	}
+}
+
+// primaryKey 指定主键字段，未标记 `pk` 时同样在零值时不插入，且不更新
+func (r *Repo[T]) primaryKey() SQLOption {
+	return func(w *sqlWrapper) {
+		if r.pkIndex != -1 {
+			w.pk = r.pk
+		}
+	}
+}
+
+func (r *Repo[T]) pkValue(data *T) (reflect.Value, bool) {
+	if data == nil || r.pkIndex == -1 {
+		return reflect.Value{}, false
+	}
+	return reflect.ValueOf(data).Elem().Field(r.pkIndex), true
+}
+
+// parsePK 返回结构体中标记 `pk` 的字段名及其索引，未标记时使用字段 id
+func parsePK(t reflect.Type) (string, int, bool) {
+	index := -1
+	for i := 0; i < t.NumField(); i++ {
+		fieldT := t.Field(i)
+
+		tag := fieldT.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		if len(name) == 0 {
+			name = strings.ToLower(fieldT.Name)
+		}
+		if opts.Contains("pk") {
+			return name, i, true
+		}
+		if name == "id" && index == -1 {
+			index = i
+		}
+	}
+	if index != -1 {
+		return "id", index, true
+	}
+	return "", -1, false
+}
@@ -0,0 +1,121 @@
+package yiigo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type repoUser struct {
+	ID   int64  `db:"id,pk"`
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func (u *repoUser) TableName() string {
+	return "user"
+}
+
+func TestRepo(t *testing.T) {
+	ctx := context.Background()
+
+	builder := sqliteBuilder(t)
+	repo := NewRepo[repoUser](builder)
+	assert.Equal(t, "user", repo.table)
+	assert.Equal(t, "id", repo.pk)
+
+	user := &repoUser{Name: "yiigo", Age: 29}
+	_, err := repo.Create(ctx, user)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), user.ID)
+
+	user.Age = 30
+	_, err = repo.Save(ctx, user)
+	assert.Nil(t, err)
+
+	record, err := repo.FindByID(ctx, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, &repoUser{ID: 1, Name: "yiigo", Age: 30}, record)
+
+	_, err = repo.Save(ctx, &repoUser{Name: "test", Age: 20})
+	assert.Nil(t, err)
+
+	records, err := repo.FindMany(ctx, Where("age >= ?", 20), OrderBy("id DESC"))
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "test", records[0].Name)
+
+	// 事务中使用
+	err = builder.Transaction(ctx, func(ctx context.Context, tx TXBuilder) error {
+		if _, err := repo.WithTx(tx).DeleteByID(ctx, 1); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.NotNil(t, err)
+
+	total, err := repo.Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), total)
+
+	_, err = repo.DeleteByID(ctx, 1)
+	assert.Nil(t, err)
+
+	_, err = repo.FindByID(ctx, 1)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+type repoNoTable struct {
+	ID int64 `db:"id"`
+}
+
+func (r *repoNoTable) TableName() string {
+	return ""
+}
+
+func TestRepoEmptyTable(t *testing.T) {
+	assert.Panics(t, func() {
+		NewRepo[repoNoTable](sqliteBuilder(t))
+	})
+}
+
+type repoUntagged struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func (r *repoUntagged) TableName() string {
+	return "user"
+}
+
+func TestRepoUntaggedPK(t *testing.T) {
+	ctx := context.Background()
+
+	repo := NewRepo[repoUntagged](sqliteBuilder(t))
+
+	a := &repoUntagged{Name: "a"}
+	_, err := repo.Create(ctx, a)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), a.ID)
+
+	b := &repoUntagged{Name: "b"}
+	_, err = repo.Create(ctx, b)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), b.ID)
+
+	b.Name = "c"
+	sql, _, err := repo.tx.Wrap(Table(repo.table), repo.primaryKey(), repo.wherePK(b.ID)).ToUpdate(b)
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "user" SET "name" = ?, "age" = ? WHERE ("id" = ?)`, sql)
+
+	_, err = repo.Save(ctx, b)
+	assert.Nil(t, err)
+
+	record, err := repo.FindByID(ctx, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "c", record.Name)
+}